@@ -2,9 +2,14 @@ package cmd
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
-	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/Drumato/helmhound/pkg/helmwrap"
 	"github.com/spf13/cobra"
 )
 
@@ -16,6 +21,11 @@ func NewCacheCommand() *cobra.Command {
 	}
 
 	cacheCmd.AddCommand(newCacheListCommand())
+	cacheCmd.AddCommand(newCacheRemoveCommand())
+	cacheCmd.AddCommand(newCachePruneCommand())
+	cacheCmd.AddCommand(newCacheVerifyCommand())
+	cacheCmd.AddCommand(newCacheExportCommand())
+	cacheCmd.AddCommand(newCacheImportCommand())
 
 	return cacheCmd
 }
@@ -26,38 +36,275 @@ func newCacheListCommand() *cobra.Command {
 		Use:   "list",
 		Short: "List cached charts",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			homeDir, err := os.UserHomeDir()
+			helmhoundDir, err := helmwrap.DefaultCacheDir()
 			if err != nil {
-				return fmt.Errorf("failed to get user home directory: %v", err)
+				return err
 			}
 
-			helmhoundDir := filepath.Join(homeDir, ".helmhound")
+			entries := helmwrap.ListCacheEntries(helmhoundDir)
+			if len(entries) == 0 {
+				fmt.Println("No cached charts found.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "URL\tVERSION\tDIGEST\tSIZE\tLAST USED")
+			for _, entry := range entries {
+				lastUsed := "-"
+				if !entry.LastUsed.IsZero() {
+					lastUsed = entry.LastUsed.Local().Format(time.DateTime)
+				}
+				digest := "-"
+				if entry.Digest != "" {
+					digest = entry.Digest[:min(len(entry.Digest), len("sha256:")+12)]
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", entry.ChartURL, entry.Version, digest, formatSize(entry.Size), lastUsed)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+// newCacheRemoveCommand creates the cache rm subcommand
+func newCacheRemoveCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rm <url> <version>",
+		Short: "Remove a cached chart",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			helmhoundDir, err := helmwrap.DefaultCacheDir()
+			if err != nil {
+				return err
+			}
+
+			entry, err := helmwrap.RemoveCacheEntry(helmhoundDir, args[0], args[1])
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Removed %s %s\n", entry.ChartURL, entry.Version)
+			return nil
+		},
+	}
+}
+
+// newCachePruneCommand creates the cache prune subcommand
+func newCachePruneCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove stale cached charts",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			olderThanFlag, err := cmd.Flags().GetString("older-than")
+			if err != nil {
+				return fmt.Errorf("failed to get older-than flag: %v", err)
+			}
+			maxSizeFlag, err := cmd.Flags().GetString("max-size")
+			if err != nil {
+				return fmt.Errorf("failed to get max-size flag: %v", err)
+			}
+
+			if olderThanFlag == "" && maxSizeFlag == "" {
+				return fmt.Errorf("at least one of --older-than or --max-size is required")
+			}
+
+			var opts helmwrap.PruneOptions
+			if olderThanFlag != "" {
+				if opts.OlderThan, err = parseAge(olderThanFlag); err != nil {
+					return fmt.Errorf("invalid --older-than: %v", err)
+				}
+			}
+			if maxSizeFlag != "" {
+				if opts.MaxSize, err = parseSize(maxSizeFlag); err != nil {
+					return fmt.Errorf("invalid --max-size: %v", err)
+				}
+			}
+
+			helmhoundDir, err := helmwrap.DefaultCacheDir()
+			if err != nil {
+				return err
+			}
+
+			pruned, err := helmwrap.PruneCache(helmhoundDir, opts, time.Now())
+			if err != nil {
+				return err
+			}
 
-			// Check if cache directory exists
-			if _, err := os.Stat(helmhoundDir); os.IsNotExist(err) {
-				fmt.Println("No cached charts found. Cache directory does not exist.")
+			if len(pruned) == 0 {
+				fmt.Println("Nothing to prune.")
 				return nil
 			}
 
-			// Read directory contents
-			entries, err := os.ReadDir(helmhoundDir)
+			var freed int64
+			for _, entry := range pruned {
+				fmt.Printf("Removed %s %s\n", entry.ChartURL, entry.Version)
+				freed += entry.Size
+			}
+			fmt.Printf("Pruned %d charts, freed %s\n", len(pruned), formatSize(freed))
+			return nil
+		},
+	}
+
+	c.Flags().String("older-than", "", "Remove charts not used within this duration (e.g. 30d, 12h)")
+	c.Flags().String("max-size", "", "Evict least recently used charts until the cache fits (e.g. 500M, 2G)")
+
+	return c
+}
+
+// newCacheVerifyCommand creates the cache verify subcommand
+func newCacheVerifyCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify",
+		Short: "Check cached charts against cache.yaml and drop orphaned entries",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			helmhoundDir, err := helmwrap.DefaultCacheDir()
 			if err != nil {
-				return fmt.Errorf("failed to read cache directory: %v", err)
+				return err
 			}
 
-			if len(entries) == 0 {
-				fmt.Println("No cached charts found.")
+			dropped, err := helmwrap.VerifyCache(helmhoundDir)
+			if err != nil {
+				return err
+			}
+			if len(dropped) == 0 {
+				fmt.Println("All cache entries are valid.")
 				return nil
 			}
 
-			fmt.Println("Cached charts:")
-			for _, entry := range entries {
-				if entry.IsDir() {
-					fmt.Printf("  - %s\n", entry.Name())
-				}
+			for _, result := range dropped {
+				fmt.Printf("Dropped %s %s: %s\n", result.Entry.ChartURL, result.Entry.Version, result.Problem)
+			}
+			return nil
+		},
+	}
+}
+
+// newCacheExportCommand creates the cache export subcommand
+func newCacheExportCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "export <file.tgz>",
+		Short: "Write cached charts to a bundle that can be imported on another machine",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			chartUrls, err := cmd.Flags().GetStringSlice("chart-url")
+			if err != nil {
+				return fmt.Errorf("failed to get chart-url flag: %v", err)
+			}
+
+			helmhoundDir, err := helmwrap.DefaultCacheDir()
+			if err != nil {
+				return err
+			}
+
+			out, err := os.Create(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to create bundle file: %v", err)
+			}
+
+			exported, err := helmwrap.ExportCache(helmhoundDir, out, chartUrls)
+			if closeErr := out.Close(); err == nil && closeErr != nil {
+				err = fmt.Errorf("failed to write bundle file: %v", closeErr)
+			}
+			if err != nil {
+				_ = os.Remove(args[0])
+				return err
+			}
+
+			for _, entry := range exported {
+				fmt.Printf("Exported %s %s\n", entry.ChartURL, entry.Version)
+			}
+			fmt.Printf("Wrote %d charts to %s\n", len(exported), args[0])
+			return nil
+		},
+	}
+
+	c.Flags().StringSlice("chart-url", nil, "Only export charts with this URL (repeatable)")
+
+	return c
+}
+
+// newCacheImportCommand creates the cache import subcommand
+func newCacheImportCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "import <path-or-tgz>",
+		Short: "Import cached charts from a bundle or a copied cache directory",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			helmhoundDir, err := helmwrap.DefaultCacheDir()
+			if err != nil {
+				return err
 			}
 
+			imported, err := helmwrap.ImportCache(helmhoundDir, args[0], slog.Default())
+			if err != nil {
+				return err
+			}
+
+			for _, entry := range imported {
+				fmt.Printf("Imported %s %s\n", entry.ChartURL, entry.Version)
+			}
+			fmt.Printf("Imported %d charts into %s\n", len(imported), helmhoundDir)
 			return nil
 		},
 	}
 }
+
+// parseAge parses a duration that additionally accepts a day suffix, e.g. "30d"
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// parseSize parses a byte size with an optional K, M, G or T suffix (powers of 1024)
+func parseSize(s string) (int64, error) {
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"T", 1 << 40},
+		{"G", 1 << 30},
+		{"M", 1 << 20},
+		{"K", 1 << 10},
+	}
+
+	number := strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(s), "B"), "I")
+	multiplier := int64(1)
+	for _, unit := range units {
+		if trimmed, ok := strings.CutSuffix(number, unit.suffix); ok {
+			number = trimmed
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// formatSize formats a byte count for display
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/Drumato/helmhound/pkg/analyzer"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed report.html
+var htmlReportTemplate string
+
+// htmlReport is the page template of the HTML output
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"resourceName":    resourceName,
+	"resourceKinds":   resourceKinds,
+	"changePath":      changePath,
+	"formatHTMLValue": formatHTMLValue,
+	"pluralize":       pluralize,
+}).Parse(htmlReportTemplate))
+
+// resourceKind is the resources of one kind in a report
+type resourceKind struct {
+	Kind      string
+	Resources []analyzer.ResourceDiff
+}
+
+// resourceKinds groups the resources of a report by kind, keeping their order by kind and name
+func resourceKinds(report *analyzer.Report) []resourceKind {
+	var kinds []resourceKind
+	for _, resource := range report.Resources {
+		if len(kinds) == 0 || kinds[len(kinds)-1].Kind != resource.Kind {
+			kinds = append(kinds, resourceKind{Kind: resource.Kind})
+		}
+		kinds[len(kinds)-1].Resources = append(kinds[len(kinds)-1].Resources, resource)
+	}
+	return kinds
+}
+
+// changePath returns the path of a change relative to its resource
+func changePath(resource analyzer.ResourceDiff, change analyzer.Change) string {
+	if change.Path == resource.Key {
+		return "(entire manifest)"
+	}
+	return strings.TrimPrefix(change.Path, resource.Key+".")
+}
+
+// formatHTMLValue formats scalars as compact JSON, and mappings, lists and multi-line strings as YAML
+func formatHTMLValue(v interface{}) string {
+	switch t := v.(type) {
+	case map[string]interface{}, []interface{}:
+	case string:
+		if !strings.Contains(t, "\n") {
+			return formatJSONValue(v)
+		}
+	default:
+		return formatJSONValue(v)
+	}
+	encoded, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSuffix(string(encoded), "\n")
+}
+
+// formatHTMLReport formats the reports as a self-contained HTML page with a collapsible tree of the changed resources.
+// The reports are also embedded as JSON, the same document the JSON output prints.
+func formatHTMLReport(reports []*analyzer.Report) (string, error) {
+	var b strings.Builder
+	if err := htmlReport.Execute(&b, reports); err != nil {
+		return "", fmt.Errorf("failed to format HTML report: %v", err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Drumato/helmhound/pkg/analyzer"
+	"github.com/Drumato/helmhound/pkg/helmwrap"
+	"github.com/Drumato/helmhound/pkg/yamldiff"
+)
+
+func TestFormatHTMLReport(t *testing.T) {
+	t.Parallel()
+
+	reports := []*analyzer.Report{
+		{
+			Chart:    analyzer.ChartRef{URL: "oci://example.com/app", Version: "1.0.0"},
+			Mutation: analyzer.Mutation{Path: "replicaCount"},
+			Value:    &helmwrap.ValueInfo{Path: "replicaCount", Type: helmwrap.ValueTypeInt, Default: 1, Line: 3, Description: "Number of <web> pods"},
+			Applied: &analyzer.AppliedMutation{
+				Strategy:     helmwrap.MutationIncrement,
+				Original:     1,
+				Modified:     2,
+				ValueChanges: []analyzer.Change{{Path: "replicaCount", Type: yamldiff.DiffTypeModified, Before: 1, After: 2}},
+			},
+			Resources: []analyzer.ResourceDiff{
+				{
+					Key:     "Deployment_web",
+					Kind:    "Deployment",
+					Name:    "web",
+					Type:    yamldiff.DiffTypeModified,
+					Changes: []analyzer.Change{{Path: "Deployment_web.spec.replicas", Type: yamldiff.DiffTypeModified, Before: 1, After: 2}},
+				},
+				{
+					Key:     "Service_web",
+					Kind:    "Service",
+					Name:    "web",
+					Type:    yamldiff.DiffTypeAdded,
+					Changes: []analyzer.Change{{Path: "Service_web", Type: yamldiff.DiffTypeAdded, After: map[string]interface{}{"kind": "Service"}}},
+				},
+			},
+		},
+		{
+			Chart:       analyzer.ChartRef{URL: "oci://example.com/app", Version: "1.0.0"},
+			Mutation:    analyzer.Mutation{Path: "image.tag"},
+			RenderError: &helmwrap.RenderError{Template: "app/templates/deployment.yaml", Line: 5, Message: "</script><script>alert(1)</script>"},
+		},
+	}
+
+	page, err := formatHTMLReport(reports)
+	if err != nil {
+		t.Fatalf("failed to format HTML report: %v", err)
+	}
+
+	for _, want := range []string{
+		"<code>replicaCount</code>",
+		"<dd>int</dd>",
+		"values.yaml:3",
+		"Number of &lt;web&gt; pods",
+		"<dt>Mutation</dt><dd>increment: <code>1</code> &rarr; <code>2</code></dd>",
+		"<summary>Deployment (1)</summary>",
+		"<code>spec.replicas</code>",
+		"<code>(entire manifest)</code>",
+		"<pre>kind: Service</pre>",
+		"<code>app/templates/deployment.yaml:5</code>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("expected the page to contain %q, got:\n%s", want, page)
+		}
+	}
+	if strings.Contains(page, "<script>alert(1)</script>") {
+		t.Errorf("expected the render error to be escaped, got:\n%s", page)
+	}
+	if strings.Contains(page, "<link") || strings.Contains(page, "src=") {
+		t.Errorf("expected a self-contained page, got:\n%s", page)
+	}
+
+	// The embedded reports are the JSON output
+	match := regexp.MustCompile(`(?s)<script type="application/json" id="helmhound-reports">(.*?)</script>`).FindStringSubmatch(page)
+	if match == nil {
+		t.Fatalf("expected the reports to be embedded as JSON, got:\n%s", page)
+	}
+	var embedded, printed interface{}
+	if err := json.Unmarshal([]byte(match[1]), &embedded); err != nil {
+		t.Fatalf("failed to decode the embedded reports: %v", err)
+	}
+	output, err := formatJSONReport(reports)
+	if err != nil {
+		t.Fatalf("failed to format JSON report: %v", err)
+	}
+	if err := json.Unmarshal([]byte(output), &printed); err != nil {
+		t.Fatalf("failed to decode the JSON output: %v", err)
+	}
+	if embeddedJSON, printedJSON := formatJSONValue(embedded), formatJSONValue(printed); embeddedJSON != printedJSON {
+		t.Errorf("expected the embedded reports %s to equal the JSON output %s", embeddedJSON, printedJSON)
+	}
+}
+
+func TestFormatHTMLValue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{name: "string", value: "docker.io", want: `"docker.io"`},
+		{name: "number", value: 2, want: "2"},
+		{name: "null", value: nil, want: "null"},
+		{name: "map", value: map[string]interface{}{"cpu": "100m"}, want: "cpu: 100m"},
+		{name: "multi-line string", value: "a\nb\n", want: "|\n    a\n    b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := formatHTMLValue(tt.value); got != tt.want {
+				t.Errorf("formatHTMLValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
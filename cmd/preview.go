@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Drumato/helmhound/pkg/analyzer"
+	"github.com/Drumato/helmhound/pkg/helmwrap"
+	"github.com/spf13/cobra"
+)
+
+// previewOptions describes the analysis the fzf preview has to reproduce for each highlighted value
+type previewOptions struct {
+	ref                    analyzer.ChartRef
+	registryOptions        helmwrap.RegistryOptions
+	valuesFile             string
+	fromRelease            string
+	validateAgainstCluster bool
+	renderTimeout          time.Duration
+	ignorePaths            []string
+	deterministic          bool
+	semantic               bool
+	diffPayloads           bool
+	revealSecrets          bool
+	sensitivePaths         []string
+}
+
+// command returns the fzf preview command, which runs the hidden preview subcommand for the highlighted value
+func (o previewOptions) command() (string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate helmhound executable: %v", err)
+	}
+
+	args := []string{
+		shellQuote(executable), "preview",
+		"--chart-url", shellQuote(o.ref.URL),
+		"--chart-version", shellQuote(o.ref.Version),
+	}
+	// The password read from stdin cannot be forwarded, but the credentials of the registry config can
+	if o.registryOptions.ConfigFile != "" {
+		args = append(args, "--registry-config", shellQuote(o.registryOptions.ConfigFile))
+	}
+	if o.registryOptions.CAFile != "" {
+		args = append(args, "--ca-file", shellQuote(o.registryOptions.CAFile))
+	}
+	if o.registryOptions.InsecureSkipTLSVerify {
+		args = append(args, "--insecure-skip-tls-verify")
+	}
+	if o.registryOptions.PlainHTTP {
+		args = append(args, "--plain-http")
+	}
+	if o.valuesFile != "" {
+		args = append(args, "--values-file", shellQuote(o.valuesFile))
+	}
+	if o.fromRelease != "" {
+		args = append(args, "--from-release", shellQuote(o.fromRelease))
+	}
+	if o.validateAgainstCluster {
+		args = append(args, "--validate-against-cluster")
+	}
+	args = append(args, "--render-timeout", o.renderTimeout.String())
+	if o.deterministic {
+		args = append(args, "--deterministic")
+	}
+	if o.semantic {
+		args = append(args, "--semantic")
+	}
+	if o.diffPayloads {
+		args = append(args, "--diff-payloads")
+	}
+	if o.revealSecrets {
+		args = append(args, "--reveal-secrets")
+	}
+	for _, sensitivePath := range o.sensitivePaths {
+		args = append(args, "--sensitive-path", shellQuote(sensitivePath))
+	}
+	for _, ignorePath := range o.ignorePaths {
+		args = append(args, "--ignore-path", shellQuote(ignorePath))
+	}
+	// fzf replaces {1} with the quoted first field of the highlighted line
+	args = append(args, "{1}")
+
+	return strings.Join(args, " "), nil
+}
+
+// shellQuote quotes s for use as a single word in a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// newPreviewCommand creates the hidden preview subcommand that fzf runs for the highlighted value
+func newPreviewCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:    "preview <value-path>",
+		Short:  "Print the impact of modifying a value (used by the fzf preview)",
+		Hidden: true,
+		Args:   cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			chartUrl, err := cmd.Flags().GetString("chart-url")
+			if err != nil {
+				return fmt.Errorf("failed to get chart-url flag: %v", err)
+			}
+			chartVersion, err := cmd.Flags().GetString("chart-version")
+			if err != nil {
+				return fmt.Errorf("failed to get chart-version flag: %v", err)
+			}
+			valuesFile, err := cmd.Flags().GetString("values-file")
+			if err != nil {
+				return fmt.Errorf("failed to get values-file flag: %v", err)
+			}
+			fromRelease, err := cmd.Flags().GetString("from-release")
+			if err != nil {
+				return fmt.Errorf("failed to get from-release flag: %v", err)
+			}
+			validateAgainstCluster, err := cmd.Flags().GetBool("validate-against-cluster")
+			if err != nil {
+				return fmt.Errorf("failed to get validate-against-cluster flag: %v", err)
+			}
+			renderTimeout, err := cmd.Flags().GetDuration("render-timeout")
+			if err != nil {
+				return fmt.Errorf("failed to get render-timeout flag: %v", err)
+			}
+			ignorePaths, err := cmd.Flags().GetStringArray("ignore-path")
+			if err != nil {
+				return fmt.Errorf("failed to get ignore-path flag: %v", err)
+			}
+			deterministic, err := cmd.Flags().GetBool("deterministic")
+			if err != nil {
+				return fmt.Errorf("failed to get deterministic flag: %v", err)
+			}
+			semantic, err := cmd.Flags().GetBool("semantic")
+			if err != nil {
+				return fmt.Errorf("failed to get semantic flag: %v", err)
+			}
+			diffPayloads, err := cmd.Flags().GetBool("diff-payloads")
+			if err != nil {
+				return fmt.Errorf("failed to get diff-payloads flag: %v", err)
+			}
+			revealSecrets, err := cmd.Flags().GetBool("reveal-secrets")
+			if err != nil {
+				return fmt.Errorf("failed to get reveal-secrets flag: %v", err)
+			}
+			sensitivePaths, err := cmd.Flags().GetStringArray("sensitive-path")
+			if err != nil {
+				return fmt.Errorf("failed to get sensitive-path flag: %v", err)
+			}
+			registryOptions, err := registryOptionsFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			// The chart has been downloaded by the selecting command, so the registry is only needed when the cluster is accessed.
+			// Progress logs would clutter the preview window.
+			a, err := analyzer.New(
+				analyzer.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+				analyzer.WithValuesFile(valuesFile),
+				analyzer.WithOffline(fromRelease == "" && !validateAgainstCluster),
+				analyzer.WithRegistryOptions(registryOptions),
+				analyzer.WithClusterValidation(validateAgainstCluster),
+				analyzer.WithRelease(fromRelease),
+				analyzer.WithRenderTimeout(renderTimeout),
+				analyzer.WithIgnorePaths(ignorePaths),
+				analyzer.WithDeterministicRender(deterministic),
+				analyzer.WithSemanticComparison(semantic),
+				analyzer.WithPayloadComparison(diffPayloads),
+				analyzer.WithRevealedSecrets(revealSecrets),
+				analyzer.WithSensitivePaths(sensitivePaths),
+			)
+			if err != nil {
+				return err
+			}
+
+			valuePath := args[0]
+			report, err := a.Analyze(cmd.Context(), analyzer.ChartRef{URL: chartUrl, Version: chartVersion}, analyzer.Mutation{Path: valuePath})
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Impact of %s\n", valuePath)
+			if report.Applied != nil {
+				fmt.Fprint(out, formatAppliedMutation(valuePath, *report.Applied))
+			}
+			fmt.Fprintln(out)
+			if report.Failed() {
+				fmt.Fprint(out, formatRenderError(report.RenderError))
+				return nil
+			}
+			if !report.HasChanges() {
+				fmt.Fprintln(out, "No differences in the rendered manifests")
+				if report.Suppressed > 0 {
+					fmt.Fprintf(out, "%d %s matched the ignore rules\n", report.Suppressed, pluralize(report.Suppressed, "difference", "differences"))
+				}
+				return nil
+			}
+			fmt.Fprint(out, formatDifferences(report))
+			return nil
+		},
+		SilenceUsage: true,
+	}
+
+	c.Flags().String("chart-url", "", "URL of the Helm chart")
+	c.Flags().String("chart-version", "", "Version of the Helm chart")
+	c.Flags().String("values-file", "", "Path to custom values file")
+	c.Flags().String("from-release", "", "Name of the deployed release used as baseline")
+	c.Flags().Bool("validate-against-cluster", false, "Validate the rendered manifests against the cluster")
+	c.Flags().Duration("render-timeout", 0, "Fail renders that take longer than this duration (0 disables)")
+	c.Flags().StringArray("ignore-path", nil, "Suppress differences at paths matching this glob or JSONPath (repeatable)")
+	addRegistryFlags(c)
+	c.Flags().Bool("deterministic", false, "Replace random and time-based template functions with fixed values")
+	c.Flags().Bool("semantic", false, "Compare quantities, durations, numbers and embedded documents by meaning")
+	c.Flags().Bool("diff-payloads", false, "Compare ConfigMap and Secret data as the files they hold")
+	c.Flags().Bool("reveal-secrets", false, "Show sensitive values instead of their fingerprints")
+	c.Flags().StringArray("sensitive-path", nil, "Redact the values at this value path (repeatable)")
+	_ = c.MarkFlagRequired("chart-url")
+	_ = c.MarkFlagRequired("chart-version")
+
+	return c
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/Drumato/helmhound/pkg/analyzer"
+	"github.com/Drumato/helmhound/pkg/helmwrap"
+)
+
+func TestShellQuote(t *testing.T) {
+	t.Parallel()
+
+	tests := []string{
+		"plain",
+		"/tmp/with space/chart",
+		"it's",
+		`$HOME "quoted" ;|&`,
+		"",
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			t.Parallel()
+
+			output, err := exec.Command("sh", "-c", "printf %s "+shellQuote(input)).Output()
+			if err != nil {
+				t.Fatalf("failed to run shell: %v", err)
+			}
+			if string(output) != input {
+				t.Errorf("shell received %q, want %q", output, input)
+			}
+		})
+	}
+}
+
+func TestPreviewOptionsCommand(t *testing.T) {
+	t.Parallel()
+
+	options := previewOptions{
+		ref: analyzer.ChartRef{URL: "oci://registry.internal/charts/app", Version: "1.2.3@sha256:0123"},
+		registryOptions: helmwrap.RegistryOptions{
+			ConfigFile:            "/home/me/registry config.json",
+			CAFile:                "/etc/ca.pem",
+			InsecureSkipTLSVerify: true,
+			PlainHTTP:             true,
+		},
+	}
+	command, err := options.command()
+	if err != nil {
+		t.Fatalf("failed to build preview command: %v", err)
+	}
+
+	// Let the shell split the arguments after the executable, then parse them as the preview subcommand would
+	_, rest, _ := strings.Cut(command, " ")
+	output, err := exec.Command("sh", "-c", "set -- "+rest+"; printf '%s\\n' \"$@\"").Output()
+	if err != nil {
+		t.Fatalf("failed to run shell: %v", err)
+	}
+	args := strings.Split(strings.TrimSuffix(string(output), "\n"), "\n")
+	if args[0] != "preview" {
+		t.Fatalf("expected the preview subcommand, got %q", args[0])
+	}
+	preview := newPreviewCommand()
+	if err := preview.ParseFlags(args[1:]); err != nil {
+		t.Fatalf("preview subcommand rejected the forwarded flags: %v", err)
+	}
+
+	chartVersion, _ := preview.Flags().GetString("chart-version")
+	if chartVersion != options.ref.Version {
+		t.Errorf("expected chart version %s, got %s", options.ref.Version, chartVersion)
+	}
+	registryOptions, err := registryOptionsFromFlags(preview)
+	if err != nil {
+		t.Fatalf("failed to read registry flags: %v", err)
+	}
+	if registryOptions != options.registryOptions {
+		t.Errorf("expected registry options %+v, got %+v", options.registryOptions, registryOptions)
+	}
+}
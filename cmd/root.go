@@ -1,21 +1,34 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/Drumato/helmhound/pkg/analyzer"
 	"github.com/Drumato/helmhound/pkg/helmwrap"
+	"github.com/Drumato/helmhound/pkg/tui"
 	"github.com/Drumato/helmhound/pkg/yamldiff"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 func New() *cobra.Command {
 	c := &cobra.Command{
 		Use:   "helmhound",
-		Short: "A Helm chart value selector using fzf.",
+		Short: "Find which rendered resources a Helm chart value affects",
+		Long: "helmhound renders a Helm chart with and without a modified value and reports the resources and fields that change.\n" +
+			"Values are selected with the built-in browser (--selector tui, the default), with fzf (--selector fzf), or directly with --value-path.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Initialize logger based on log-level flag
 			logLevel, err := cmd.Flags().GetString("log-level")
@@ -59,33 +72,36 @@ func New() *cobra.Command {
 				return fmt.Errorf("chart-version is required")
 			}
 
-			client, err := helmwrap.NewClient()
+			refresh, err := cmd.Flags().GetBool("refresh")
 			if err != nil {
-				return fmt.Errorf("failed to create helm client: %v", err)
+				return fmt.Errorf("failed to get refresh flag: %v", err)
 			}
 
-			slog.Info("Downloading chart...")
-			chartPath, chartName, err := client.DownloadChart(chartUrl, chartVersion)
+			offline, err := cmd.Flags().GetBool("offline")
 			if err != nil {
-				return fmt.Errorf("failed to download chart: %v", err)
+				return fmt.Errorf("failed to get offline flag: %v", err)
+			}
+			if env := os.Getenv("HELMHOUND_OFFLINE"); env != "" && !cmd.Flags().Changed("offline") {
+				offline, err = strconv.ParseBool(env)
+				if err != nil {
+					return fmt.Errorf("invalid HELMHOUND_OFFLINE value %q: %v", env, err)
+				}
 			}
 
-			slog.Debug("Chart downloaded", "path", chartPath, "name", chartName)
-
-			slog.Info("Reading chart values...")
-			values, err := client.ReadValuesFromChart(chartPath, chartName)
+			validateAgainstCluster, err := cmd.Flags().GetBool("validate-against-cluster")
 			if err != nil {
-				return fmt.Errorf("failed to read chart values: %v", err)
+				return fmt.Errorf("failed to get validate-against-cluster flag: %v", err)
 			}
 
-			slog.Debug("Values extracted", "length", len(values))
-
-			valuePaths, err := helmwrap.ExtractValuePaths(values)
+			fromRelease, err := cmd.Flags().GetString("from-release")
 			if err != nil {
-				return fmt.Errorf("failed to extract value paths: %v", err)
+				return fmt.Errorf("failed to get from-release flag: %v", err)
 			}
 
-			slog.Debug("Value paths extracted", "count", len(valuePaths))
+			registryOptions, err := registryOptionsFromFlags(cmd)
+			if err != nil {
+				return err
+			}
 
 			valuePath, err := cmd.Flags().GetString("value-path")
 			if err != nil {
@@ -96,73 +112,214 @@ func New() *cobra.Command {
 			if err != nil {
 				return fmt.Errorf("failed to get values-file flag: %v", err)
 			}
+			if fromRelease != "" && valuesFile != "" {
+				return fmt.Errorf("--values-file cannot be combined with --from-release, whose deployed values are the baseline")
+			}
 
-			var selectedPath string
-			if valuePath != "" {
-				selectedPath = valuePath
-			} else {
-				selectedPath, err = selectValueWithFzf(valuePaths)
-				if err != nil {
-					return fmt.Errorf("failed to select value: %v", err)
-				}
+			selector, err := cmd.Flags().GetString("selector")
+			if err != nil {
+				return fmt.Errorf("failed to get selector flag: %v", err)
+			}
+
+			multi, err := cmd.Flags().GetBool("multi")
+			if err != nil {
+				return fmt.Errorf("failed to get multi flag: %v", err)
+			}
+
+			timeout, err := cmd.Flags().GetDuration("timeout")
+			if err != nil {
+				return fmt.Errorf("failed to get timeout flag: %v", err)
+			}
+
+			renderTimeout, err := cmd.Flags().GetDuration("render-timeout")
+			if err != nil {
+				return fmt.Errorf("failed to get render-timeout flag: %v", err)
+			}
+			continueOnRenderError, err := cmd.Flags().GetBool("continue-on-render-error")
+			if err != nil {
+				return fmt.Errorf("failed to get continue-on-render-error flag: %v", err)
 			}
 
-			// Get the type of the selected value and log it
-			valueType, err := helmwrap.GetValueType(values, selectedPath)
+			format, err := cmd.Flags().GetString("format")
 			if err != nil {
-				slog.Debug("Failed to get value type", "path", selectedPath, "error", err)
-			} else {
-				slog.Debug("Value type detected", "path", selectedPath, "type", valueTypeToString(valueType))
+				return fmt.Errorf("failed to get format flag: %v", err)
+			}
+			switch format {
+			case formatPaths, formatUnified, formatSideBySide, formatJSONPatch, formatMergePatch:
+			default:
+				return fmt.Errorf("unknown format %q (available: paths, unified, side-by-side, json-patch, merge-patch)", format)
 			}
 
-			fmt.Printf("Selected value path: %s\n", selectedPath)
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				return fmt.Errorf("failed to get output flag: %v", err)
+			}
+			switch output {
+			case outputText, outputJSON, outputHTML:
+			default:
+				return fmt.Errorf("unknown output %q (available: text, json, html)", output)
+			}
 
-			// Render original template
-			slog.Info("Rendering original template...")
-			originalManifest, err := client.RenderTemplate(chartPath, chartName, valuesFile)
+			ignorePaths, err := ignorePathsFromFlags(cmd)
 			if err != nil {
-				return fmt.Errorf("failed to render original template: %v", err)
+				return err
 			}
 
-			slog.Debug("Original template rendered", "manifest_keys", len(originalManifest))
+			deterministic, err := cmd.Flags().GetBool("deterministic")
+			if err != nil {
+				return fmt.Errorf("failed to get deterministic flag: %v", err)
+			}
 
-			// Render template with modified value
-			slog.Info("Rendering template with modified value...")
-			modifiedManifest, err := client.RenderTemplateWithModifiedValue(chartPath, chartName, selectedPath, valuesFile)
+			semantic, err := cmd.Flags().GetBool("semantic")
 			if err != nil {
-				return fmt.Errorf("failed to render template with modified value: %v", err)
+				return fmt.Errorf("failed to get semantic flag: %v", err)
 			}
 
-			slog.Debug("Modified template rendered", "manifest_keys", len(modifiedManifest))
+			diffPayloads, err := cmd.Flags().GetBool("diff-payloads")
+			if err != nil {
+				return fmt.Errorf("failed to get diff-payloads flag: %v", err)
+			}
 
-			// Compare manifests and find differences
-			slog.Info("Comparing manifests...")
-			groupedDiffs := yamldiff.CompareYAMLGroupedDetailed(originalManifest, modifiedManifest)
+			revealSecrets, err := cmd.Flags().GetBool("reveal-secrets")
+			if err != nil {
+				return fmt.Errorf("failed to get reveal-secrets flag: %v", err)
+			}
 
-			if len(groupedDiffs) == 0 {
-				fmt.Printf("No differences found in the rendered manifests for path '%s'.\n", selectedPath)
-				fmt.Println("This suggests that the selected value path may not affect the template rendering.")
-				fmt.Println("The value might be:")
-				fmt.Println("  - Used only in specific conditions that are not met")
-				fmt.Println("  - A configuration option that doesn't impact manifest generation")
-				fmt.Println("  - An unused or deprecated field in the chart")
-				return nil
+			sensitivePaths, err := cmd.Flags().GetStringArray("sensitive-path")
+			if err != nil {
+				return fmt.Errorf("failed to get sensitive-path flag: %v", err)
 			}
 
-			totalPaths := 0
-			for _, items := range groupedDiffs {
-				totalPaths += len(items)
+			a, err := analyzer.New(
+				analyzer.WithLogger(logger),
+				analyzer.WithValuesFile(valuesFile),
+				analyzer.WithRefresh(refresh),
+				analyzer.WithOffline(offline),
+				analyzer.WithRegistryOptions(registryOptions),
+				analyzer.WithClusterValidation(validateAgainstCluster),
+				analyzer.WithRelease(fromRelease),
+				analyzer.WithRenderTimeout(renderTimeout),
+				analyzer.WithIgnorePaths(ignorePaths),
+				analyzer.WithDeterministicRender(deterministic),
+				analyzer.WithSemanticComparison(semantic),
+				analyzer.WithPayloadComparison(diffPayloads),
+				analyzer.WithRevealedSecrets(revealSecrets),
+				analyzer.WithSensitivePaths(sensitivePaths),
+			)
+			if err != nil {
+				return err
 			}
 
-			fmt.Printf("\nDifferences found (%d paths):\n", totalPaths)
-			for manifestKey, items := range groupedDiffs {
-				fmt.Printf("%s:\n", manifestKey)
-				for _, item := range items {
-					fmt.Printf("  - %s\n", item.DisplayText)
+			ref := analyzer.ChartRef{URL: chartUrl, Version: chartVersion}
+
+			// The run timeout does not count the time spent in interactive selection
+			started := time.Now()
+			ctx, cancel := withOptionalTimeout(cmd.Context(), timeout)
+			defer cancel()
+
+			// Downloads the chart and renders the baseline
+			valueInfos, err := a.Values(ctx, ref)
+			if err != nil {
+				return timeoutError(ctx, err, timeout)
+			}
+			remaining := timeout - time.Since(started)
+
+			var selectedPaths []string
+			switch {
+			case valuePath != "":
+				selectedPaths = []string{valuePath}
+			case selector == "fzf":
+				// The offline preview cannot resolve version ranges, so it gets the version of the downloaded chart
+				var previewRef analyzer.ChartRef
+				previewRef, err = a.Pinned(ctx, ref)
+				if err != nil {
+					return timeoutError(ctx, err, timeout)
 				}
-				fmt.Println()
+				selectedPaths, err = selectValueWithFzf(valueInfos, multi, previewOptions{
+					ref:                    previewRef,
+					registryOptions:        registryOptions,
+					valuesFile:             valuesFile,
+					fromRelease:            fromRelease,
+					validateAgainstCluster: validateAgainstCluster,
+					renderTimeout:          renderTimeout,
+					ignorePaths:            ignorePaths,
+					deterministic:          deterministic,
+					semantic:               semantic,
+					diffPayloads:           diffPayloads,
+					revealSecrets:          revealSecrets,
+					sensitivePaths:         sensitivePaths,
+				})
+			case selector == "tui":
+				selectedPaths, err = selectValueWithTUI(cmd.Context(), a, ref, valueInfos, multi)
+			default:
+				return fmt.Errorf("unknown selector %q (available: tui, fzf)", selector)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to select value: %v", err)
+			}
+
+			if timeout > 0 {
+				ctx, cancel = withOptionalTimeout(cmd.Context(), max(remaining, time.Nanosecond))
+				defer cancel()
 			}
 
+			failed := 0
+			var reports []*analyzer.Report
+			var renderErr error
+			for i, selectedPath := range selectedPaths {
+				if i > 0 && output == outputText {
+					fmt.Println()
+				}
+				report, err := a.Analyze(ctx, ref, analyzer.Mutation{Path: selectedPath})
+				var renderTimeoutErr *analyzer.RenderTimeoutError
+				var mutationErr *analyzer.MutationError
+				if errors.As(err, &renderTimeoutErr) || errors.As(err, &mutationErr) {
+					// A hung render or an unusable value path fails its own value path instead of the whole batch
+					if output == outputText {
+						fmt.Printf("Selected value path: %s\nFailed: %v\n", selectedPath, err)
+					} else {
+						logger.Error("Failed to analyze value path", "path", selectedPath, "error", err)
+					}
+					failed++
+					continue
+				}
+				if err != nil {
+					return timeoutError(ctx, err, timeout)
+				}
+				reports = append(reports, report)
+				if output == outputText {
+					fmt.Print(formatReport(report, format, terminalWidth()))
+				}
+				if report.Failed() {
+					if !continueOnRenderError {
+						renderErr = fmt.Errorf("rendering with %s modified failed", selectedPath)
+						break
+					}
+					failed++
+				}
+			}
+
+			switch output {
+			case outputJSON:
+				encoded, err := formatJSONReport(reports)
+				if err != nil {
+					return err
+				}
+				fmt.Print(encoded)
+			case outputHTML:
+				page, err := formatHTMLReport(reports)
+				if err != nil {
+					return err
+				}
+				fmt.Print(page)
+			}
+
+			if renderErr != nil {
+				return renderErr
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d value paths failed", failed, len(selectedPaths))
+			}
 			return nil
 		},
 		SilenceUsage:  true,
@@ -170,57 +327,475 @@ func New() *cobra.Command {
 	}
 
 	c.Flags().String("chart-url", "", "URL of the Helm chart")
-	c.Flags().String("chart-version", "", "Version of the Helm chart")
+	c.Flags().String("chart-version", "", "Version of the Helm chart; pin content with \"@sha256:<digest>\" or \"<version>@sha256:<digest>\"")
 	c.Flags().String("value-path", "", "Specific value path to search for (skips interactive selection)")
+	c.Flags().String("selector", "tui", "Interactive value selector: tui (built-in browser) or fzf")
+	c.Flags().Bool("multi", false, "Select several value paths and analyze each of them")
+	c.Flags().Duration("timeout", 0, "Abort the run after this duration, not counting interactive selection (0 disables)")
+	c.Flags().Duration("render-timeout", 2*time.Minute, "Fail a value path whose render takes longer than this duration (0 disables)")
+	c.Flags().Bool("continue-on-render-error", false, "Report value paths whose modification breaks rendering and continue with the remaining paths")
+	c.Flags().String("format", formatPaths, "Output format of the differences: paths, unified, side-by-side, json-patch or merge-patch")
+	c.Flags().String("output", outputText, "Output of the reports: text, json or html (a self-contained page); --format applies to text")
+	c.Flags().StringArray("ignore-path", nil, "Suppress differences at paths matching this glob or JSONPath (repeatable)")
+	c.Flags().Bool("deterministic", false, "Replace random and time-based template functions (randAlphaNum, uuidv4, now, genCA, ...) with fixed values so they do not show up as differences")
+	c.Flags().Bool("semantic", false, "Treat equal quantities (1000m and 1), durations (60s and 1m) and numbers (1 and 1.0) as unchanged and diff JSON/YAML documents embedded in strings field by field")
+	c.Flags().Bool("diff-payloads", false, "Compare ConfigMap and Secret data as the YAML, JSON, INI or text files they hold, decoding Secret data")
+	c.Flags().StringArray("sensitive-path", nil, "Redact the values at this value path and below it in all outputs, in addition to Secret data and credential-like keys (repeatable)")
+	c.Flags().Bool("reveal-secrets", false, "Disable redaction and show Secret data and other sensitive values instead of their fingerprints")
+	c.Flags().String("ignore-config", "", "File with default ignore rules (default: ~/.helmhound/ignore.yaml if it exists)")
 	c.Flags().String("values-file", "", "Path to custom values.yaml file to merge with chart defaults")
 	c.Flags().String("log-level", "info", "Log level (debug, info, warn, error)")
+	c.Flags().Bool("refresh", false, "Re-resolve the chart version and replace the cached chart if its digest changed")
+	c.Flags().Bool("offline", false, "Serve charts only from the cache and never access the network (env: HELMHOUND_OFFLINE)")
+	c.Flags().Bool("validate-against-cluster", false, "Render with the capabilities of the current kubeconfig's cluster and validate manifests against its API server")
+	c.Flags().String("from-release", "", "Compare against the manifest of this deployed release, using its values as the baseline")
+	addRegistryFlags(c)
 
 	// Add cache subcommand
 	c.AddCommand(NewCacheCommand())
+	c.AddCommand(newPreviewCommand())
 
 	return c
 }
 
-func selectValueWithFzf(values []string) (string, error) {
-	if len(values) == 0 {
-		return "", fmt.Errorf("no values to select from")
+// withOptionalTimeout returns ctx bounded by timeout, or ctx itself if timeout is zero
+func withOptionalTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// timeoutError replaces err with a readable message when the run timeout has passed
+func timeoutError(ctx context.Context, err error, timeout time.Duration) error {
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() != nil {
+		return fmt.Errorf("helmhound did not finish within --timeout %s", timeout)
 	}
+	return err
+}
 
-	cmd := exec.Command("fzf", "--prompt=Select value: ")
-	cmd.Stdin = strings.NewReader(strings.Join(values, "\n"))
+// Output formats of the differences
+const (
+	// formatPaths lists the changed paths of each resource
+	formatPaths = "paths"
+	// formatUnified prints a diff -u style hunk per resource
+	formatUnified = "unified"
+	// formatSideBySide prints the changed lines of each resource in two columns
+	formatSideBySide = "side-by-side"
+	// formatJSONPatch prints an RFC 6902 JSON Patch per modified resource
+	formatJSONPatch = "json-patch"
+	// formatMergePatch prints an RFC 7386 merge patch per modified resource
+	formatMergePatch = "merge-patch"
+)
+
+// Outputs of the reports
+const (
+	// outputText prints each report in the format selected with --format
+	outputText = "text"
+	// outputJSON prints the reports as a JSON array
+	outputJSON = "json"
+	// outputHTML prints the reports as a self-contained HTML page
+	outputHTML = "html"
+)
+
+// formatJSONReport formats the reports as an indented JSON array
+func formatJSONReport(reports []*analyzer.Report) (string, error) {
+	if reports == nil {
+		reports = []*analyzer.Report{}
+	}
+	encoded, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to format JSON report: %v", err)
+	}
+	return string(encoded) + "\n", nil
+}
+
+// formatReport formats the analyzed value followed by the differences its mutation caused in the given format.
+// width is the line width of the side-by-side format.
+func formatReport(report *analyzer.Report, format string, width int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Selected value path: %s\n", report.Mutation.Path)
+	if report.Value != nil {
+		b.WriteString(formatValueInfo(*report.Value))
+	}
+	if report.Applied != nil {
+		b.WriteString(formatAppliedMutation(report.Mutation.Path, *report.Applied))
+	}
+
+	if report.Failed() {
+		b.WriteString("\n" + formatRenderError(report.RenderError))
+		return b.String()
+	}
+
+	if !report.HasChanges() {
+		fmt.Fprintf(&b, "No differences found in the rendered manifests for path '%s'.\n", report.Mutation.Path)
+		if report.Suppressed > 0 {
+			fmt.Fprintf(&b, "%d %s matched the ignore rules.\n", report.Suppressed, pluralize(report.Suppressed, "difference", "differences"))
+			return b.String()
+		}
+		b.WriteString("This suggests that the selected value path may not affect the template rendering.\n")
+		b.WriteString("The value might be:\n")
+		b.WriteString("  - Used only in specific conditions that are not met\n")
+		b.WriteString("  - A configuration option that doesn't impact manifest generation\n")
+		b.WriteString("  - An unused or deprecated field in the chart\n")
+		return b.String()
+	}
+
+	switch format {
+	case formatUnified:
+		b.WriteString("\n" + formatResourceCounts(report) + "\n" + formatUnifiedDiff(report))
+	case formatSideBySide:
+		b.WriteString("\n" + formatResourceCounts(report) + "\n" + formatSideBySideDiff(report, width))
+	case formatJSONPatch, formatMergePatch:
+		b.WriteString("\n" + formatPatches(report, format))
+	default:
+		b.WriteString("\n" + formatDifferences(report))
+	}
+	return b.String()
+}
+
+// formatValueInfo formats where a value is defined in values.yaml, its default and its description
+func formatValueInfo(info helmwrap.ValueInfo) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Defined at: values.yaml:%d\n", info.Line)
+	fmt.Fprintf(&b, "Default: %s\n", info.Summary())
+	if info.Description != "" {
+		fmt.Fprintf(&b, "Description: %s\n", info.Description)
+	}
+	return b.String()
+}
+
+// formatAppliedMutation formats the strategy and result of a mutation. The changed values are listed as well
+// when the mutation changed more than the value itself, e.g. a global propagated to subcharts.
+func formatAppliedMutation(path string, applied analyzer.AppliedMutation) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Applied mutation: %s (%s -> %s)\n", applied.Strategy, formatJSONValue(applied.Original), formatJSONValue(applied.Modified))
+	if len(applied.ValueChanges) == 0 || (len(applied.ValueChanges) == 1 && applied.ValueChanges[0].Path == path) {
+		return b.String()
+	}
+
+	b.WriteString("Value changes:\n")
+	for _, change := range applied.ValueChanges {
+		switch change.Type {
+		case yamldiff.DiffTypeAdded:
+			fmt.Fprintf(&b, "  + %s: %s\n", change.Path, formatJSONValue(change.After))
+		case yamldiff.DiffTypeRemoved:
+			fmt.Fprintf(&b, "  - %s: %s\n", change.Path, formatJSONValue(change.Before))
+		default:
+			fmt.Fprintf(&b, "  ~ %s: %s -> %s\n", change.Path, formatJSONValue(change.Before), formatJSONValue(change.After))
+		}
+	}
+	return b.String()
+}
+
+// formatJSONValue formats a value as compact JSON, falling back to its Go representation
+func formatJSONValue(v interface{}) string {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(encoded)
+}
+
+// selectValueWithTUI lets the user browse the chart values with a live preview of each value's impact
+func selectValueWithTUI(ctx context.Context, a *analyzer.Analyzer, ref analyzer.ChartRef, valueInfos []helmwrap.ValueInfo, multi bool) ([]string, error) {
+	impact := func(path string) (string, error) {
+		report, err := a.Analyze(ctx, ref, analyzer.Mutation{Path: path})
+		if err != nil {
+			return "", err
+		}
+		if report.Failed() {
+			return formatRenderError(report.RenderError), nil
+		}
+		if !report.HasChanges() {
+			return "", nil
+		}
+		return formatDifferences(report), nil
+	}
+
+	return tui.Select(valueInfos, impact, multi)
+}
+
+// formatDifferences summarizes the added, removed and modified resources, followed by the changed paths of each modified resource
+func formatDifferences(report *analyzer.Report) string {
+	added := report.ResourcesByType(yamldiff.DiffTypeAdded)
+	removed := report.ResourcesByType(yamldiff.DiffTypeRemoved)
+	modified := report.ResourcesByType(yamldiff.DiffTypeModified)
+
+	var b strings.Builder
+	b.WriteString(formatResourceCounts(report))
+	for _, group := range []struct {
+		title     string
+		resources []analyzer.ResourceDiff
+	}{
+		{title: "Added", resources: added},
+		{title: "Removed", resources: removed},
+		{title: "Modified", resources: modified},
+	} {
+		if len(group.resources) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s:\n", group.title)
+		for _, resource := range group.resources {
+			if group.title != "Modified" {
+				fmt.Fprintf(&b, "  %s\n", resourceName(resource))
+				continue
+			}
+			fmt.Fprintf(&b, "  %s (%d %s)\n", resourceName(resource), len(resource.Changes), pluralize(len(resource.Changes), "path", "paths"))
+			for _, change := range resource.Changes {
+				path := strings.TrimPrefix(change.Path, resource.Key+".")
+				if change.Path == resource.Key {
+					path = "(affects entire manifest)"
+				}
+				fmt.Fprintf(&b, "    - %s\n", path)
+			}
+		}
+	}
+	return b.String()
+}
+
+// formatResourceCounts formats the number of added, removed and modified resources and of suppressed differences
+func formatResourceCounts(report *analyzer.Report) string {
+	added := len(report.ResourcesByType(yamldiff.DiffTypeAdded))
+	removed := len(report.ResourcesByType(yamldiff.DiffTypeRemoved))
+	modified := len(report.ResourcesByType(yamldiff.DiffTypeModified))
+	counts := fmt.Sprintf("Added: %d %s / Removed: %d / Modified: %d\n", added, pluralize(added, "resource", "resources"), removed, modified)
+	if report.Suppressed > 0 {
+		counts += fmt.Sprintf("Suppressed: %d %s matching the ignore rules\n", report.Suppressed, pluralize(report.Suppressed, "difference", "differences"))
+	}
+	return counts
+}
+
+// formatPatches formats the resource counts followed by a JSON Patch or merge patch per modified resource.
+// Added and removed resources have no patch.
+func formatPatches(report *analyzer.Report, format string) string {
+	var b strings.Builder
+	b.WriteString(formatResourceCounts(report))
+	for _, resource := range report.ResourcesByType(yamldiff.DiffTypeModified) {
+		var patch interface{}
+		if format == formatJSONPatch {
+			if operations := resource.JSONPatch(); operations != nil {
+				patch = operations
+			}
+		} else if mergePatch := resource.MergePatch(); mergePatch != nil {
+			patch = mergePatch
+		}
+		if patch == nil {
+			// Documents that are not mappings cannot be patched
+			fmt.Fprintf(&b, "\n%s: no patch for a document that is not a mapping\n", resourceName(resource))
+			continue
+		}
+
+		encoded, err := json.MarshalIndent(patch, "", "  ")
+		if err != nil {
+			fmt.Fprintf(&b, "\n%s: failed to encode patch: %v\n", resourceName(resource), err)
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s:\n%s\n", resourceName(resource), encoded)
+	}
+	return b.String()
+}
+
+// formatUnifiedDiff formats a diff -u style hunk per changed resource
+func formatUnifiedDiff(report *analyzer.Report) string {
+	var b strings.Builder
+	for _, resource := range report.Resources {
+		b.WriteString(yamldiff.UnifiedDiff(resource.Key, resource.Before, resource.After))
+	}
+	return b.String()
+}
+
+// formatSideBySideDiff formats the changed lines of each changed resource in two columns fitting width
+func formatSideBySideDiff(report *analyzer.Report, width int) string {
+	var b strings.Builder
+	for i, resource := range report.Resources {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(yamldiff.SideBySideDiff(resource.Key, resource.Before, resource.After, width))
+	}
+	return b.String()
+}
+
+// terminalWidth returns the width of the terminal on stdout, or 160 columns if stdout is not a terminal
+func terminalWidth() int {
+	if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+		return width
+	}
+	return 160
+}
+
+// resourceName returns "Kind/name" of a resource, or its manifest key if the manifest has no kind or name
+func resourceName(resource analyzer.ResourceDiff) string {
+	if resource.Kind == "" || resource.Name == "" {
+		return resource.Key
+	}
+	return resource.Kind + "/" + resource.Name
+}
+
+// pluralize returns singular if count is one and plural otherwise
+func pluralize(count int, singular, plural string) string {
+	if count == 1 {
+		return singular
+	}
+	return plural
+}
+
+// formatRenderError formats why the chart failed to render with the modified value and where
+func formatRenderError(renderErr *helmwrap.RenderError) string {
+	var b strings.Builder
+	b.WriteString("Rendering failed with the modified value:\n")
+	if location := renderErr.Location(); location != "" {
+		fmt.Fprintf(&b, "  Template: %s\n", location)
+	}
+	fmt.Fprintf(&b, "  Message: %s\n", renderErr.Message)
+	return b.String()
+}
+
+// selectValueWithFzf lets the user pick value paths with fzf, previewing the impact of the highlighted path
+func selectValueWithFzf(valueInfos []helmwrap.ValueInfo, multi bool, preview previewOptions) ([]string, error) {
+	if len(valueInfos) == 0 {
+		return nil, fmt.Errorf("no values to select from")
+	}
+
+	previewCommand, err := preview.command()
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{
+		"--prompt=Select value: ",
+		"--delimiter=\t",
+		"--preview=" + previewCommand,
+		"--preview-window=right,50%,wrap",
+	}
+	if multi {
+		args = append(args, "--multi")
+	}
+
+	cmd := exec.Command("fzf", args...)
+	cmd.Stdin = strings.NewReader(formatFzfLines(valueInfos))
 	cmd.Stderr = os.Stderr
 
 	output, err := cmd.Output()
 	if err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
 			if exitError.ExitCode() == 130 { // User canceled (Ctrl+C)
-				return "", fmt.Errorf("selection canceled")
+				return nil, fmt.Errorf("selection canceled")
 			}
 		}
-		return "", fmt.Errorf("fzf execution failed: %v", err)
+		return nil, fmt.Errorf("fzf execution failed: %v", err)
 	}
 
-	selected := strings.TrimSpace(string(output))
-	if selected == "" {
-		return "", fmt.Errorf("no value selected")
+	selected := parseFzfSelection(string(output))
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no value selected")
 	}
 
 	return selected, nil
 }
 
-func valueTypeToString(vt helmwrap.ValueType) string {
-	switch vt {
-	case helmwrap.ValueTypeString:
-		return "string"
-	case helmwrap.ValueTypeInt:
-		return "int"
-	case helmwrap.ValueTypeBool:
-		return "bool"
-	case helmwrap.ValueTypeSlice:
-		return "slice"
-	case helmwrap.ValueTypeMap:
-		return "map"
-	default:
-		return "unknown"
+// formatFzfLines formats one tab separated line per value: path, type and default value, and comment
+func formatFzfLines(valueInfos []helmwrap.ValueInfo) string {
+	var b strings.Builder
+	for _, info := range valueInfos {
+		b.WriteString(info.Path + "\t" + info.Summary())
+		if info.Description != "" {
+			b.WriteString("\t# " + info.Description)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// parseFzfSelection returns the value paths of the lines fzf printed
+func parseFzfSelection(output string) []string {
+	var paths []string
+	for _, line := range strings.Split(output, "\n") {
+		path, _, _ := strings.Cut(line, "\t")
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// ignorePathsFromFlags returns the patterns of the ignore rules file followed by those of --ignore-path.
+// Without --ignore-config, ~/.helmhound/ignore.yaml is read if it exists.
+func ignorePathsFromFlags(cmd *cobra.Command) ([]string, error) {
+	ignorePaths, err := cmd.Flags().GetStringArray("ignore-path")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ignore-path flag: %v", err)
 	}
+	ignoreConfig, err := cmd.Flags().GetString("ignore-config")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ignore-config flag: %v", err)
+	}
+
+	required := ignoreConfig != ""
+	if !required {
+		dir, err := helmwrap.DefaultCacheDir()
+		if err != nil {
+			return ignorePaths, nil
+		}
+		ignoreConfig = filepath.Join(dir, "ignore.yaml")
+	}
+
+	patterns, err := yamldiff.ReadIgnoreFile(ignoreConfig)
+	if err != nil {
+		if !required && errors.Is(err, fs.ErrNotExist) {
+			return ignorePaths, nil
+		}
+		return nil, fmt.Errorf("failed to read ignore rules: %v", err)
+	}
+	return append(patterns, ignorePaths...), nil
+}
+
+// addRegistryFlags adds the flags read by registryOptionsFromFlags
+func addRegistryFlags(c *cobra.Command) {
+	c.Flags().String("registry-config", "", "Path to the registry credentials file (default: helm's registry/config.json)")
+	c.Flags().String("username", "", "Username for the chart registry or repository")
+	c.Flags().Bool("password-stdin", false, "Read the registry password from stdin")
+	c.Flags().String("ca-file", "", "Verify registry certificates using this CA bundle")
+	c.Flags().Bool("insecure-skip-tls-verify", false, "Skip TLS certificate verification of the registry")
+	c.Flags().Bool("plain-http", false, "Use plain HTTP instead of HTTPS to access the registry")
+}
+
+// registryOptionsFromFlags builds the registry options from the command flags, reading the password from stdin if requested
+func registryOptionsFromFlags(cmd *cobra.Command) (helmwrap.RegistryOptions, error) {
+	var opts helmwrap.RegistryOptions
+	var err error
+
+	if opts.ConfigFile, err = cmd.Flags().GetString("registry-config"); err != nil {
+		return opts, fmt.Errorf("failed to get registry-config flag: %v", err)
+	}
+	if opts.Username, err = cmd.Flags().GetString("username"); err != nil {
+		return opts, fmt.Errorf("failed to get username flag: %v", err)
+	}
+	if opts.CAFile, err = cmd.Flags().GetString("ca-file"); err != nil {
+		return opts, fmt.Errorf("failed to get ca-file flag: %v", err)
+	}
+	if opts.InsecureSkipTLSVerify, err = cmd.Flags().GetBool("insecure-skip-tls-verify"); err != nil {
+		return opts, fmt.Errorf("failed to get insecure-skip-tls-verify flag: %v", err)
+	}
+	if opts.PlainHTTP, err = cmd.Flags().GetBool("plain-http"); err != nil {
+		return opts, fmt.Errorf("failed to get plain-http flag: %v", err)
+	}
+
+	passwordStdin, err := cmd.Flags().GetBool("password-stdin")
+	if err != nil {
+		return opts, fmt.Errorf("failed to get password-stdin flag: %v", err)
+	}
+	if passwordStdin {
+		if opts.Username == "" {
+			return opts, fmt.Errorf("--password-stdin requires --username")
+		}
+		password, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return opts, fmt.Errorf("failed to read password from stdin: %v", err)
+		}
+		opts.Password = strings.TrimRight(string(password), "\r\n")
+	}
+
+	return opts, nil
 }
@@ -0,0 +1,412 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Drumato/helmhound/pkg/analyzer"
+	"github.com/Drumato/helmhound/pkg/helmwrap"
+	"github.com/Drumato/helmhound/pkg/yamldiff"
+)
+
+func TestFzfLines(t *testing.T) {
+	t.Parallel()
+
+	infos := []helmwrap.ValueInfo{
+		{Path: "replicaCount", Type: helmwrap.ValueTypeInt, Default: 1, Description: "Number of pods"},
+		{Path: "image.tag", Type: helmwrap.ValueTypeString, Default: "1.27"},
+	}
+
+	lines := formatFzfLines(infos)
+	want := "replicaCount\tint = 1\t# Number of pods\nimage.tag\tstring = \"1.27\"\n"
+	if lines != want {
+		t.Errorf("formatFzfLines() = %q, want %q", lines, want)
+	}
+
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{name: "single line", output: "replicaCount\tint = 1\t# Number of pods\n", want: []string{"replicaCount"}},
+		{name: "multi select", output: lines, want: []string{"replicaCount", "image.tag"}},
+		{name: "empty output", output: "\n", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := parseFzfSelection(tt.output)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("parseFzfSelection(%q) = %q, want %q", tt.output, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatDifferences(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		report *analyzer.Report
+		want   string
+	}{
+		{
+			name: "added, removed and modified",
+			report: &analyzer.Report{
+				Resources: []analyzer.ResourceDiff{
+					{
+						Key:  "ConfigMap_old",
+						Kind: "ConfigMap",
+						Name: "old",
+						Type: yamldiff.DiffTypeRemoved,
+						Changes: []analyzer.Change{
+							{Path: "ConfigMap_old", Type: yamldiff.DiffTypeRemoved},
+						},
+					},
+					{
+						Key:  "Deployment_web",
+						Kind: "Deployment",
+						Name: "web",
+						Type: yamldiff.DiffTypeModified,
+						Changes: []analyzer.Change{
+							{Path: "Deployment_web.metadata.labels.tier", Type: yamldiff.DiffTypeAdded},
+							{Path: "Deployment_web.spec.replicas", Type: yamldiff.DiffTypeModified},
+						},
+					},
+					{
+						Key:  "Service_web",
+						Kind: "Service",
+						Name: "web",
+						Type: yamldiff.DiffTypeAdded,
+						Changes: []analyzer.Change{
+							{Path: "Service_web", Type: yamldiff.DiffTypeAdded},
+						},
+					},
+				},
+			},
+			want: `Added: 1 resource / Removed: 1 / Modified: 1
+
+Added:
+  Service/web
+
+Removed:
+  ConfigMap/old
+
+Modified:
+  Deployment/web (2 paths)
+    - metadata.labels.tier
+    - spec.replicas
+`,
+		},
+		{
+			name: "only added resources",
+			report: &analyzer.Report{
+				Resources: []analyzer.ResourceDiff{
+					{Key: "Service_a", Kind: "Service", Name: "a", Type: yamldiff.DiffTypeAdded},
+					{Key: "document_3", Type: yamldiff.DiffTypeAdded},
+				},
+			},
+			want: `Added: 2 resources / Removed: 0 / Modified: 0
+
+Added:
+  Service/a
+  document_3
+`,
+		},
+		{
+			name: "replaced document",
+			report: &analyzer.Report{
+				Resources: []analyzer.ResourceDiff{
+					{
+						Key:     "document_2",
+						Type:    yamldiff.DiffTypeModified,
+						Changes: []analyzer.Change{{Path: "document_2", Type: yamldiff.DiffTypeModified}},
+					},
+				},
+			},
+			want: `Added: 0 resources / Removed: 0 / Modified: 1
+
+Modified:
+  document_2 (1 path)
+    - (affects entire manifest)
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := formatDifferences(tt.report); got != tt.want {
+				t.Errorf("formatDifferences() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatRenderError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		renderErr *helmwrap.RenderError
+		want      string
+	}{
+		{
+			name:      "with location",
+			renderErr: &helmwrap.RenderError{Template: "app/templates/deployment.yaml", Line: 5, Column: 14, Message: "image.tag is required"},
+			want:      "Rendering failed with the modified value:\n  Template: app/templates/deployment.yaml:5:14\n  Message: image.tag is required\n",
+		},
+		{
+			name:      "without location",
+			renderErr: &helmwrap.RenderError{Message: "chart requires kubeVersion: >=1.30.0"},
+			want:      "Rendering failed with the modified value:\n  Message: chart requires kubeVersion: >=1.30.0\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := formatRenderError(tt.renderErr); got != tt.want {
+				t.Errorf("formatRenderError() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatAppliedMutation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		path    string
+		applied analyzer.AppliedMutation
+		want    string
+	}{
+		{
+			name: "only the mutated value changed",
+			path: "replicaCount",
+			applied: analyzer.AppliedMutation{
+				Strategy:     helmwrap.MutationIncrement,
+				Original:     1,
+				Modified:     2,
+				ValueChanges: []analyzer.Change{{Path: "replicaCount", Type: yamldiff.DiffTypeModified, Before: 1, After: 2}},
+			},
+			want: "Applied mutation: increment (1 -> 2)\n",
+		},
+		{
+			name: "global propagated to a subchart",
+			path: "global.registry",
+			applied: analyzer.AppliedMutation{
+				Strategy: helmwrap.MutationPrefix,
+				Original: "docker.io",
+				Modified: "helmhound-test-docker.io",
+				ValueChanges: []analyzer.Change{
+					{Path: "global.registry", Type: yamldiff.DiffTypeModified, Before: "docker.io", After: "helmhound-test-docker.io"},
+					{Path: "redis.global.registry", Type: yamldiff.DiffTypeModified, Before: "docker.io", After: "helmhound-test-docker.io"},
+				},
+			},
+			want: "Applied mutation: prefix (\"docker.io\" -> \"helmhound-test-docker.io\")\n" +
+				"Value changes:\n" +
+				"  ~ global.registry: \"docker.io\" -> \"helmhound-test-docker.io\"\n" +
+				"  ~ redis.global.registry: \"docker.io\" -> \"helmhound-test-docker.io\"\n",
+		},
+		{
+			name: "added key",
+			path: "podLabels",
+			applied: analyzer.AppliedMutation{
+				Strategy:     helmwrap.MutationAddKey,
+				Original:     map[string]interface{}{},
+				Modified:     map[string]interface{}{"helmhound-test-key": "helmhound-test-value"},
+				ValueChanges: []analyzer.Change{{Path: "podLabels.helmhound-test-key", Type: yamldiff.DiffTypeAdded, After: "helmhound-test-value"}},
+			},
+			want: "Applied mutation: add-key ({} -> {\"helmhound-test-key\":\"helmhound-test-value\"})\n" +
+				"Value changes:\n" +
+				"  + podLabels.helmhound-test-key: \"helmhound-test-value\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := formatAppliedMutation(tt.path, tt.applied); got != tt.want {
+				t.Errorf("formatAppliedMutation() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatReportFormats(t *testing.T) {
+	t.Parallel()
+
+	report := &analyzer.Report{
+		Mutation: analyzer.Mutation{Path: "replicaCount"},
+		Resources: []analyzer.ResourceDiff{
+			{
+				Key:     "Deployment_web",
+				Kind:    "Deployment",
+				Name:    "web",
+				Type:    yamldiff.DiffTypeModified,
+				Changes: []analyzer.Change{{Path: "Deployment_web.spec.replicas", Type: yamldiff.DiffTypeModified}},
+				Before:  map[string]interface{}{"spec": map[string]interface{}{"replicas": 1}},
+				After:   map[string]interface{}{"spec": map[string]interface{}{"replicas": 2}},
+			},
+		},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: formatPaths,
+			want:   "Selected value path: replicaCount\n\nAdded: 0 resources / Removed: 0 / Modified: 1\n\nModified:\n  Deployment/web (1 path)\n    - spec.replicas\n",
+		},
+		{
+			format: formatUnified,
+			want:   "Selected value path: replicaCount\n\nAdded: 0 resources / Removed: 0 / Modified: 1\n\n--- a/Deployment_web\n+++ b/Deployment_web\n@@ -1,2 +1,2 @@\n spec:\n-  replicas: 1\n+  replicas: 2\n",
+		},
+		{
+			format: formatSideBySide,
+			want:   "Selected value path: replicaCount\n\nAdded: 0 resources / Removed: 0 / Modified: 1\n\nDeployment_web\n@@ -1,2 +1,2 @@\nspec:            spec:\n  replicas: 1  |   replicas: 2\n",
+		},
+		{
+			format: formatJSONPatch,
+			want:   "Selected value path: replicaCount\n\nAdded: 0 resources / Removed: 0 / Modified: 1\n\nDeployment/web:\n[\n  {\n    \"op\": \"replace\",\n    \"path\": \"/spec/replicas\",\n    \"value\": 2\n  }\n]\n",
+		},
+		{
+			format: formatMergePatch,
+			want:   "Selected value path: replicaCount\n\nAdded: 0 resources / Removed: 0 / Modified: 1\n\nDeployment/web:\n{\n  \"spec\": {\n    \"replicas\": 2\n  }\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			t.Parallel()
+
+			if got := formatReport(report, tt.format, 31); got != tt.want {
+				t.Errorf("formatReport() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnorePathsFromFlags(t *testing.T) {
+	t.Parallel()
+
+	config := filepath.Join(t.TempDir(), "ignore.yaml")
+	if err := os.WriteFile(config, []byte("ignore:\n  - \"**.checksum/*\"\n"), 0o644); err != nil {
+		t.Fatalf("failed to write ignore rules: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "config file and flags",
+			args: []string{"--ignore-config", config, "--ignore-path", "$..caBundle", "--ignore-path", "Secret_*.data"},
+			want: []string{"**.checksum/*", "$..caBundle", "Secret_*.data"},
+		},
+		{
+			name:    "missing config file",
+			args:    []string{"--ignore-config", filepath.Join(t.TempDir(), "missing.yaml")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cmd := New()
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatalf("failed to parse flags: %v", err)
+			}
+			got, err := ignorePathsFromFlags(cmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ignorePathsFromFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ignorePathsFromFlags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatReportSuppressed(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		format string
+		report *analyzer.Report
+		want   string
+	}{
+		{
+			name:   "all differences suppressed",
+			report: &analyzer.Report{Mutation: analyzer.Mutation{Path: "podAnnotations"}, Suppressed: 2},
+			want:   "Selected value path: podAnnotations\nNo differences found in the rendered manifests for path 'podAnnotations'.\n2 differences matched the ignore rules.\n",
+		},
+		{
+			name: "some differences suppressed",
+			report: &analyzer.Report{
+				Mutation: analyzer.Mutation{Path: "replicaCount"},
+				Resources: []analyzer.ResourceDiff{
+					{
+						Key:     "Deployment_web",
+						Kind:    "Deployment",
+						Name:    "web",
+						Type:    yamldiff.DiffTypeModified,
+						Changes: []analyzer.Change{{Path: "Deployment_web.spec.replicas"}},
+					},
+				},
+				Suppressed: 1,
+			},
+			want: "Selected value path: replicaCount\n\nAdded: 0 resources / Removed: 0 / Modified: 1\nSuppressed: 1 difference matching the ignore rules\n\nModified:\n  Deployment/web (1 path)\n    - spec.replicas\n",
+		},
+		{
+			name:   "unified diff with differences suppressed",
+			format: formatUnified,
+			report: &analyzer.Report{
+				Mutation: analyzer.Mutation{Path: "replicaCount"},
+				Resources: []analyzer.ResourceDiff{
+					{
+						Key:     "Deployment_web",
+						Kind:    "Deployment",
+						Name:    "web",
+						Type:    yamldiff.DiffTypeModified,
+						Changes: []analyzer.Change{{Path: "Deployment_web.spec.replicas"}},
+						Before:  map[string]interface{}{"spec": map[string]interface{}{"replicas": 1}},
+						After:   map[string]interface{}{"spec": map[string]interface{}{"replicas": 2}},
+					},
+				},
+				Suppressed: 1,
+			},
+			want: "Selected value path: replicaCount\n\nAdded: 0 resources / Removed: 0 / Modified: 1\nSuppressed: 1 difference matching the ignore rules\n\n--- a/Deployment_web\n+++ b/Deployment_web\n@@ -1,2 +1,2 @@\n spec:\n-  replicas: 1\n+  replicas: 2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			format := tt.format
+			if format == "" {
+				format = formatPaths
+			}
+			if got := formatReport(tt.report, format, 80); got != tt.want {
+				t.Errorf("formatReport() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
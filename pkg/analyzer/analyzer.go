@@ -0,0 +1,479 @@
+// Package analyzer finds out which rendered manifests of a Helm chart change when one of its values is modified.
+// It is the library behind the helmhound command.
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Drumato/helmhound/pkg/helmwrap"
+	"github.com/Drumato/helmhound/pkg/yamldiff"
+)
+
+// ChartRef identifies a chart by its URL and version
+type ChartRef struct {
+	// URL is an OCI reference ("oci://...") or the URL of a chart archive in a chart repository
+	URL string `json:"url"`
+	// Version is the chart version; pin content with "@sha256:<digest>" or "<version>@sha256:<digest>"
+	Version string `json:"version"`
+}
+
+// Mutation describes how the chart values are modified
+type Mutation struct {
+	// Path is the value path to modify, e.g. "image.tag", or "service.ports[0].port" for an element of a list
+	Path string `json:"path"`
+}
+
+// Analyzer renders charts with and without a mutation and reports the differences.
+// An Analyzer may be used for many analyses; the chart download and baseline render are reused between them.
+type Analyzer struct {
+	client        helmwrap.Client
+	logger        *slog.Logger
+	valuesFile    string
+	release       string
+	renderTimeout time.Duration
+	ignoreRules   *yamldiff.IgnoreRules
+	compare       yamldiff.CompareOptions
+	// sensitivePaths are the value paths whose values are redacted; redaction is disabled if revealSecrets is set
+	sensitivePaths []string
+	revealSecrets  bool
+
+	mu     sync.Mutex
+	charts map[ChartRef]*preparedChart
+}
+
+// preparedChart is a downloaded chart together with its values and baseline manifests
+type preparedChart struct {
+	dir  string
+	name string
+	// values are redacted, since they are output as they are
+	values   []helmwrap.ValueInfo
+	baseline map[string]interface{}
+	redactor *yamldiff.Redactor
+}
+
+type config struct {
+	logger         *slog.Logger
+	valuesFile     string
+	release        string
+	renderTimeout  time.Duration
+	ignorePaths    []string
+	compare        yamldiff.CompareOptions
+	sensitivePaths []string
+	revealSecrets  bool
+	clientOptions  []helmwrap.ClientOption
+}
+
+// Option configures an Analyzer created by New
+type Option func(*config)
+
+// WithLogger sets the logger progress and warnings are written to (default: slog.Default())
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// WithValuesFile merges the values file over the chart defaults before rendering
+func WithValuesFile(path string) Option {
+	return func(c *config) {
+		c.valuesFile = path
+	}
+}
+
+// WithRenderTimeout bounds every render of the chart; renders that take longer fail with a *RenderTimeoutError.
+// Helm's template engine cannot be interrupted, so a timed-out render keeps running in the background until it
+// finishes. Zero disables the timeout.
+func WithRenderTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.renderTimeout = timeout
+	}
+}
+
+// WithIgnorePaths suppresses differences at paths matching the patterns, which are globs such as
+// "metadata.annotations.checksum/*" or JSONPaths such as "$..caBundle". Reports count the suppressed differences.
+func WithIgnorePaths(patterns []string) Option {
+	return func(c *config) {
+		c.ignorePaths = append(c.ignorePaths, patterns...)
+	}
+}
+
+// WithSemanticComparison treats values with the same meaning as unchanged, e.g. the quantities "1000m" and "1",
+// and compares JSON and YAML documents embedded in strings field by field
+func WithSemanticComparison(semantic bool) Option {
+	return func(c *config) {
+		c.compare.Semantic = semantic
+	}
+}
+
+// WithPayloadComparison compares the data of ConfigMaps and Secrets as the YAML, JSON, INI or text files they hold,
+// decoding Secret data
+func WithPayloadComparison(payloads bool) Option {
+	return func(c *config) {
+		c.compare.Payloads = payloads
+	}
+}
+
+// WithSensitivePaths redacts the values at the value paths, and everything below them, in addition to Secret data
+// and values whose keys are named like credentials. Rendered strings containing their values are redacted too.
+func WithSensitivePaths(paths []string) Option {
+	return func(c *config) {
+		c.sensitivePaths = append(c.sensitivePaths, paths...)
+	}
+}
+
+// WithRevealedSecrets disables redaction, so that reports and values show sensitive values instead of their fingerprints
+func WithRevealedSecrets(reveal bool) Option {
+	return func(c *config) {
+		c.revealSecrets = reveal
+	}
+}
+
+// WithCacheDir overrides the directory charts are cached in (default: ~/.helmhound)
+func WithCacheDir(dir string) Option {
+	return func(c *config) {
+		c.clientOptions = append(c.clientOptions, helmwrap.WithCacheDir(dir))
+	}
+}
+
+// WithRefresh re-resolves chart versions instead of trusting the cache
+func WithRefresh(refresh bool) Option {
+	return func(c *config) {
+		c.clientOptions = append(c.clientOptions, helmwrap.WithRefresh(refresh))
+	}
+}
+
+// WithOffline serves charts only from the cache and never accesses the network
+func WithOffline(offline bool) Option {
+	return func(c *config) {
+		c.clientOptions = append(c.clientOptions, helmwrap.WithOffline(offline))
+	}
+}
+
+// WithRegistryOptions configures authentication and transport security for chart downloads
+func WithRegistryOptions(opts helmwrap.RegistryOptions) Option {
+	return func(c *config) {
+		c.clientOptions = append(c.clientOptions, helmwrap.WithRegistryOptions(opts))
+	}
+}
+
+// WithClusterValidation renders with the capabilities of the current kubeconfig's cluster and validates the manifests against it
+func WithClusterValidation(validate bool) Option {
+	return func(c *config) {
+		c.clientOptions = append(c.clientOptions, helmwrap.WithClusterValidation(validate))
+	}
+}
+
+// WithDeterministicRender replaces random and time-based template functions with fixed values in every render
+func WithDeterministicRender(deterministic bool) Option {
+	return func(c *config) {
+		c.clientOptions = append(c.clientOptions, helmwrap.WithDeterministicRender(deterministic))
+	}
+}
+
+// WithRelease compares against the manifest of the named deployed release, using its values as the baseline
+// instead of a values file. The chart must be analyzed at the version the release was deployed with.
+func WithRelease(name string) Option {
+	return func(c *config) {
+		c.release = name
+		c.clientOptions = append(c.clientOptions, helmwrap.WithRelease(name))
+	}
+}
+
+// New creates an Analyzer
+func New(opts ...Option) (*Analyzer, error) {
+	cfg := config{logger: slog.Default()}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.release != "" && cfg.valuesFile != "" {
+		return nil, fmt.Errorf("a values file cannot be combined with a release baseline")
+	}
+
+	ignoreRules, err := yamldiff.NewIgnoreRules(cfg.ignorePaths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ignore paths: %v", err)
+	}
+
+	client, err := helmwrap.NewClient(append(cfg.clientOptions, helmwrap.WithLogger(cfg.logger))...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create helm client: %v", err)
+	}
+
+	return &Analyzer{
+		client:         client,
+		logger:         cfg.logger,
+		valuesFile:     cfg.valuesFile,
+		release:        cfg.release,
+		renderTimeout:  cfg.renderTimeout,
+		ignoreRules:    ignoreRules,
+		compare:        cfg.compare,
+		sensitivePaths: cfg.sensitivePaths,
+		revealSecrets:  cfg.revealSecrets,
+		charts:         make(map[ChartRef]*preparedChart),
+	}, nil
+}
+
+// Values returns the values of the chart in the order of its values.yaml
+func (a *Analyzer) Values(ctx context.Context, ref ChartRef) ([]helmwrap.ValueInfo, error) {
+	chart, err := a.prepare(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return chart.values, nil
+}
+
+// Pinned downloads the chart and returns ref with its version pinned to the downloaded content, e.g. "1.2.3@sha256:...",
+// so that analyzers working offline from the same cache directory find the chart even if ref names a version range
+func (a *Analyzer) Pinned(ctx context.Context, ref ChartRef) (ChartRef, error) {
+	chart, err := a.prepare(ctx, ref)
+	if err != nil {
+		return ChartRef{}, err
+	}
+
+	for _, entry := range helmwrap.ListCacheEntries(chart.dir) {
+		if entry.ChartName != chart.name || entry.ChartURL != ref.URL {
+			continue
+		}
+		if entry.Digest == "" {
+			// Entries written by older versions do not record their digest
+			return ChartRef{URL: ref.URL, Version: entry.Version}, nil
+		}
+		return ChartRef{URL: ref.URL, Version: entry.Version + "@" + entry.Digest}, nil
+	}
+	return ref, nil
+}
+
+// Analyze renders the chart with the mutation applied and reports how the manifests differ from the baseline.
+// Progress is only logged at debug level, since Analyze runs once per value while browsing values.
+// The report describes the applied modification, and if the modified render fails, it carries the RenderError
+// instead of resource changes.
+// If the value path cannot be modified, a *MutationError is returned.
+// If ctx is canceled, the download or render is aborted and ctx.Err() is returned.
+func (a *Analyzer) Analyze(ctx context.Context, ref ChartRef, mutation Mutation) (*Report, error) {
+	if mutation.Path == "" {
+		return nil, fmt.Errorf("mutation path is required")
+	}
+
+	chart, err := a.prepare(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Chart:     ref,
+		Mutation:  mutation,
+		Resources: []ResourceDiff{},
+	}
+	for _, info := range chart.values {
+		if info.Path == mutation.Path {
+			value := info
+			report.Value = &value
+			break
+		}
+	}
+
+	a.logger.Debug("Rendering template with modified value", "path", mutation.Path)
+	var modified map[string]interface{}
+	var applied *helmwrap.AppliedMutation
+	err = a.render(ctx, mutation.Path, func(ctx context.Context) (err error) {
+		modified, applied, err = a.client.RenderTemplateWithModifiedValue(ctx, chart.dir, chart.name, mutation.Path, a.valuesFile)
+		return err
+	})
+	if applied != nil {
+		report.Applied = newAppliedMutation(applied, chart.redactor, a.sensitivePaths)
+	}
+	// A mutation that breaks rendering is a result of the analysis, not a failure of it
+	var renderErr *helmwrap.RenderError
+	if errors.As(err, &renderErr) {
+		a.logger.Debug("Rendering with modified value failed", "path", mutation.Path, "error", renderErr.Err)
+		report.RenderError = renderErr
+		return report, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report.Resources, report.Suppressed = diffResources(chart.baseline, modified, a.compare, a.ignoreRules)
+	redactResources(report.Resources, chart.redactor)
+	return report, nil
+}
+
+// prepare downloads the chart, reads its values and renders the baseline once per chart
+func (a *Analyzer) prepare(ctx context.Context, ref ChartRef) (*preparedChart, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if chart, ok := a.charts[ref]; ok {
+		return chart, nil
+	}
+
+	if ref.URL == "" {
+		return nil, fmt.Errorf("chart URL is required")
+	}
+	if ref.Version == "" {
+		return nil, fmt.Errorf("chart version is required")
+	}
+
+	a.logger.Info("Downloading chart...", "url", ref.URL, "version", ref.Version)
+	dir, name, err := a.client.DownloadChart(ctx, ref.URL, ref.Version)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to download chart: %v", err)
+	}
+	a.logger.Debug("Chart downloaded", "path", dir, "name", name)
+
+	a.logger.Info("Reading chart values...")
+	valuesYaml, err := a.client.ReadValuesFromChart(dir, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chart values: %v", err)
+	}
+	values, err := helmwrap.DescribeValuePaths(valuesYaml)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract value paths: %v", err)
+	}
+	a.logger.Debug("Value paths extracted", "count", len(values))
+
+	redactor, err := a.newRedactor(values)
+	if err != nil {
+		return nil, err
+	}
+	values = redactValues(values, redactor, a.sensitivePaths)
+
+	// Render original template, or take the deployed manifest when comparing against a release
+	if a.release != "" {
+		a.logger.Info("Using deployed release as baseline...", "release", a.release)
+	} else {
+		a.logger.Info("Rendering original template...")
+	}
+	var baseline map[string]interface{}
+	err = a.render(ctx, "", func(ctx context.Context) (err error) {
+		baseline, err = a.client.RenderTemplate(ctx, dir, name, a.valuesFile)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.logger.Debug("Original template rendered", "manifest_keys", len(baseline))
+
+	chart := &preparedChart{dir: dir, name: name, values: values, baseline: baseline, redactor: redactor}
+	a.charts[ref] = chart
+	return chart, nil
+}
+
+// render runs fn with ctx bounded by the render timeout. path is the mutated value path, or empty for the baseline.
+func (a *Analyzer) render(ctx context.Context, path string, fn func(ctx context.Context) error) error {
+	renderCtx, cancel := ctx, context.CancelFunc(func() {})
+	if a.renderTimeout > 0 {
+		renderCtx, cancel = context.WithTimeout(ctx, a.renderTimeout)
+	}
+	defer cancel()
+
+	err := fn(renderCtx)
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case renderCtx.Err() != nil:
+		return &RenderTimeoutError{Path: path, Timeout: a.renderTimeout}
+	case path == "":
+		return fmt.Errorf("failed to render original template: %v", err)
+	case errors.As(err, new(*helmwrap.RenderError)):
+		return err
+	default:
+		return &MutationError{Path: path, Err: err}
+	}
+}
+
+// diffResources compares the manifests and returns the changed resources ordered by kind and name,
+// together with the number of differences the ignore rules suppressed
+func diffResources(baseline, modified map[string]interface{}, opts yamldiff.CompareOptions, ignoreRules *yamldiff.IgnoreRules) ([]ResourceDiff, int) {
+	values, suppressed := ignoreRules.Filter(yamldiff.FindDifferencesWithOptions(baseline, modified, opts))
+	grouped := yamldiff.GroupDifferencesDetailed(baseline, modified, values)
+
+	resources := make([]ResourceDiff, 0, len(grouped))
+	for _, key := range grouped.ManifestKeys() {
+		manifest := modified[key]
+		if manifest == nil {
+			manifest = baseline[key]
+		}
+		kind, name := resourceIdentity(manifest)
+
+		resource := ResourceDiff{
+			Key:  key,
+			Kind: kind,
+			Name: name,
+			Type: yamldiff.DiffTypeModified,
+			// Ignored fields are left out of the manifests so that text diffs and patches skip them as well
+			Before: ignoreRules.Strip(key, baseline[key]),
+			After:  ignoreRules.Strip(key, modified[key]),
+		}
+		for _, item := range grouped[key] {
+			diff := values[item.Path]
+			if item.Path == key && diff.Type != yamldiff.DiffTypeModified {
+				resource.Type = diff.Type
+				diff.Left, diff.Right = resource.Before, resource.After
+			}
+			resource.Changes = append(resource.Changes, Change{
+				Path:    item.Path,
+				Pointer: diff.Pointer,
+				Type:    diff.Type,
+				Before:  diff.Left,
+				After:   diff.Right,
+			})
+		}
+		resources = append(resources, resource)
+	}
+	return resources, suppressed
+}
+
+// newAppliedMutation reports the mutation applied by the client with sensitive values redacted
+func newAppliedMutation(applied *helmwrap.AppliedMutation, redactor *yamldiff.Redactor, sensitivePaths []string) *AppliedMutation {
+	redact := func(path string, v interface{}) interface{} {
+		if redactor != nil && sensitiveValuePath(path, sensitivePaths) {
+			return yamldiff.RedactLeaves(v)
+		}
+		return redactor.Redact(v)
+	}
+
+	mutation := &AppliedMutation{
+		Strategy:     applied.Strategy,
+		Original:     redact(applied.Path, applied.Original),
+		Modified:     redact(applied.Path, applied.Modified),
+		ValueChanges: []Change{},
+	}
+	diffs := yamldiff.FindDifferencesWithValues(applied.BaselineValues, applied.ModifiedValues)
+	for _, path := range yamldiff.SortedPaths(diffs) {
+		diff := diffs[path]
+		mutation.ValueChanges = append(mutation.ValueChanges, Change{
+			Path:    path,
+			Pointer: diff.Pointer,
+			Type:    diff.Type,
+			Before:  redact(path, diff.Left),
+			After:   redact(path, diff.Right),
+		})
+	}
+	return mutation
+}
+
+// resourceIdentity returns the kind and name of a parsed manifest
+func resourceIdentity(manifest interface{}) (string, string) {
+	doc, ok := manifest.(map[string]interface{})
+	if !ok {
+		return "", ""
+	}
+	kind, _ := doc["kind"].(string)
+	var name string
+	if metadata, ok := doc["metadata"].(map[string]interface{}); ok {
+		name, _ = metadata["name"].(string)
+	}
+	return kind, name
+}
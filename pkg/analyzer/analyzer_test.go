@@ -0,0 +1,524 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Drumato/helmhound/pkg/helmwrap"
+	"github.com/Drumato/helmhound/pkg/yamldiff"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// serveTestChart packages a chart with a Deployment taking a list of arguments, a Service, a template that fails for modes other than "standard"
+// and a template that renders slowly when "hang" is true, and serves it over HTTP
+func serveTestChart(t *testing.T) ChartRef {
+	t.Helper()
+
+	values := `# -- Number of pods
+replicaCount: 1
+service:
+  enabled: true
+  port: 80
+# -- Container arguments
+args:
+  - --verbose
+# -- Deployment mode
+mode: standard
+# -- Renders a template that never finishes
+hang: false
+`
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .Release.Name }}-app
+spec:
+  replicas: {{ .Values.replicaCount }}
+  template:
+    spec:
+      containers:
+        - name: app
+          args: {{ toJson .Values.args }}
+`
+	service := `{{- if .Values.service.enabled }}
+apiVersion: v1
+kind: Service
+metadata:
+  name: {{ .Release.Name }}-app
+spec:
+  ports:
+    - port: {{ .Values.service.port }}
+{{- end }}
+`
+	mode := `{{- if ne .Values.mode "standard" }}
+{{- fail (printf "unsupported mode %s" .Values.mode) }}
+{{- end }}
+`
+	// Slow enough to exceed the render timeout of the tests, but finishing afterwards so that the abandoned
+	// render does not keep a CPU busy for the rest of the package run
+	hang := `{{- if .Values.hang }}{{ range until 2000 }}{{ range until 10000 }}{{ end }}{{ end }}{{- end }}
+`
+
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "app", Version: "1.0.0"},
+		Raw:      []*chart.File{{Name: chartutil.ValuesfileName, Data: []byte(values)}},
+		Templates: []*chart.File{
+			{Name: "templates/deployment.yaml", Data: []byte(deployment)},
+			{Name: "templates/service.yaml", Data: []byte(service)},
+			{Name: "templates/mode.yaml", Data: []byte(mode)},
+			{Name: "templates/hang.yaml", Data: []byte(hang)},
+		},
+	}
+
+	archiveDir := t.TempDir()
+	if _, err := chartutil.Save(c, archiveDir); err != nil {
+		t.Fatalf("failed to package test chart: %v", err)
+	}
+	server := httptest.NewServer(http.FileServer(http.Dir(archiveDir)))
+	t.Cleanup(server.Close)
+
+	return ChartRef{URL: server.URL + "/app-1.0.0.tgz", Version: "1.0.0"}
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestAnalyze(t *testing.T) {
+	t.Parallel()
+
+	ref := serveTestChart(t)
+	var logs bytes.Buffer
+	a, err := New(WithCacheDir(t.TempDir()), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	if err != nil {
+		t.Fatalf("failed to create analyzer: %v", err)
+	}
+
+	values, err := a.Values(t.Context(), ref)
+	if err != nil {
+		t.Fatalf("failed to read values: %v", err)
+	}
+	var paths []string
+	for _, value := range values {
+		paths = append(paths, value.Path)
+	}
+	if strings.Join(paths, ",") != "replicaCount,service,service.enabled,service.port,args,args[0],mode,hang" {
+		t.Errorf("unexpected value paths %v", paths)
+	}
+	if !strings.Contains(logs.String(), "Downloading chart") {
+		t.Errorf("expected progress in the configured logger, got %q", logs.String())
+	}
+
+	tests := []struct {
+		path          string
+		wantResources []string
+		wantTypes     []yamldiff.DiffType
+		wantChanges   int
+		wantStrategy  helmwrap.MutationStrategy
+		wantValues    []string
+	}{
+		{
+			path:          "replicaCount",
+			wantResources: []string{"Deployment_helmhound-render-app"},
+			wantTypes:     []yamldiff.DiffType{yamldiff.DiffTypeModified},
+			wantChanges:   1,
+			wantStrategy:  helmwrap.MutationIncrement,
+			wantValues:    []string{"replicaCount"},
+		},
+		{
+			path:          "service.enabled",
+			wantResources: []string{"Service_helmhound-render-app"},
+			wantTypes:     []yamldiff.DiffType{yamldiff.DiffTypeRemoved},
+			wantChanges:   1,
+			wantStrategy:  helmwrap.MutationToggle,
+			wantValues:    []string{"service.enabled"},
+		},
+		{
+			path:          "args[0]",
+			wantResources: []string{"Deployment_helmhound-render-app"},
+			wantTypes:     []yamldiff.DiffType{yamldiff.DiffTypeModified},
+			wantChanges:   1,
+			wantStrategy:  helmwrap.MutationPrefix,
+			wantValues:    []string{"args[0]"},
+		},
+		{
+			path:         "service",
+			wantStrategy: helmwrap.MutationAddKey,
+			wantValues:   []string{"service.helmhound-test-key"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			t.Parallel()
+
+			report, err := a.Analyze(t.Context(), ref, Mutation{Path: tt.path})
+			if err != nil {
+				t.Fatalf("failed to analyze: %v", err)
+			}
+
+			var keys []string
+			var types []yamldiff.DiffType
+			for _, resource := range report.Resources {
+				keys = append(keys, resource.Key)
+				types = append(types, resource.Type)
+			}
+			if strings.Join(keys, ",") != strings.Join(tt.wantResources, ",") {
+				t.Errorf("expected resources %v, got %v", tt.wantResources, keys)
+			}
+			if !slices.Equal(types, tt.wantTypes) {
+				t.Errorf("expected resource types %v, got %v", tt.wantTypes, types)
+			}
+			if report.ChangeCount() != tt.wantChanges {
+				t.Errorf("expected %d changes, got %d", tt.wantChanges, report.ChangeCount())
+			}
+			if report.Value == nil || report.Value.Path != tt.path {
+				t.Errorf("expected the report to describe %s, got %+v", tt.path, report.Value)
+			}
+
+			if report.Applied == nil {
+				t.Fatalf("expected the applied mutation to be reported")
+			}
+			if report.Applied.Strategy != tt.wantStrategy {
+				t.Errorf("expected strategy %s, got %s", tt.wantStrategy, report.Applied.Strategy)
+			}
+			var valuePaths []string
+			for _, change := range report.Applied.ValueChanges {
+				valuePaths = append(valuePaths, change.Path)
+			}
+			if !slices.Equal(valuePaths, tt.wantValues) {
+				t.Errorf("expected value changes %v, got %v", tt.wantValues, valuePaths)
+			}
+		})
+	}
+}
+
+func TestNewRejectsValuesFileWithRelease(t *testing.T) {
+	t.Parallel()
+
+	if _, err := New(WithValuesFile("values.yaml"), WithRelease("web"), WithLogger(discardLogger())); err == nil {
+		t.Fatal("expected error for a values file combined with a release")
+	}
+}
+
+func TestAnalyzeChange(t *testing.T) {
+	t.Parallel()
+
+	ref := serveTestChart(t)
+	a, err := New(WithCacheDir(t.TempDir()), WithLogger(discardLogger()))
+	if err != nil {
+		t.Fatalf("failed to create analyzer: %v", err)
+	}
+
+	report, err := a.Analyze(t.Context(), ref, Mutation{Path: "replicaCount"})
+	if err != nil {
+		t.Fatalf("failed to analyze: %v", err)
+	}
+
+	if len(report.Resources) != 1 || len(report.Resources[0].Changes) != 1 {
+		t.Fatalf("expected a single change, got %+v", report.Resources)
+	}
+	resource := report.Resources[0]
+	if resource.Kind != "Deployment" || resource.Name != "helmhound-render-app" {
+		t.Errorf("expected Deployment helmhound-render-app, got %s %s", resource.Kind, resource.Name)
+	}
+	change := resource.Changes[0]
+	if change.Path != "Deployment_helmhound-render-app.spec.replicas" || change.Type != yamldiff.DiffTypeModified || change.Before != 1 || change.After != 2 {
+		t.Errorf("unexpected change %+v", change)
+	}
+	if report.Value.Description != "Number of pods" {
+		t.Errorf("expected the value description, got %q", report.Value.Description)
+	}
+}
+
+func TestAnalyzeRenderError(t *testing.T) {
+	t.Parallel()
+
+	ref := serveTestChart(t)
+	a, err := New(WithCacheDir(t.TempDir()), WithLogger(discardLogger()))
+	if err != nil {
+		t.Fatalf("failed to create analyzer: %v", err)
+	}
+
+	report, err := a.Analyze(t.Context(), ref, Mutation{Path: "mode"})
+	if err != nil {
+		t.Fatalf("expected the render failure in the report, got %v", err)
+	}
+	if !report.Failed() || report.HasChanges() {
+		t.Fatalf("expected a failed report without changes, got %+v", report)
+	}
+	renderErr := report.RenderError
+	if renderErr.Template != "app/templates/mode.yaml" || renderErr.Line != 2 || renderErr.Message != "unsupported mode helmhound-test-standard" {
+		t.Errorf("unexpected render error %+v", renderErr)
+	}
+	if report.Value == nil || report.Value.Description != "Deployment mode" {
+		t.Errorf("expected the report to describe mode, got %+v", report.Value)
+	}
+}
+
+func TestAnalyzeMutationError(t *testing.T) {
+	t.Parallel()
+
+	ref := serveTestChart(t)
+	a, err := New(WithCacheDir(t.TempDir()), WithLogger(discardLogger()))
+	if err != nil {
+		t.Fatalf("failed to create analyzer: %v", err)
+	}
+
+	_, err = a.Analyze(t.Context(), ref, Mutation{Path: "service.missing"})
+	var mutationErr *MutationError
+	if !errors.As(err, &mutationErr) {
+		t.Fatalf("expected a MutationError, got %v", err)
+	}
+	if mutationErr.Path != "service.missing" {
+		t.Errorf("expected the error to name service.missing, got %s", mutationErr.Path)
+	}
+}
+
+func TestAnalyzeCanceled(t *testing.T) {
+	t.Parallel()
+
+	ref := serveTestChart(t)
+	a, err := New(WithCacheDir(t.TempDir()), WithLogger(discardLogger()))
+	if err != nil {
+		t.Fatalf("failed to create analyzer: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if _, err := a.Analyze(ctx, ref, Mutation{Path: "replicaCount"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestPinned(t *testing.T) {
+	t.Parallel()
+
+	ref := serveTestChart(t)
+	ref.Version = ">=1.0.0"
+	cacheDir := t.TempDir()
+	a, err := New(WithCacheDir(cacheDir), WithLogger(discardLogger()))
+	if err != nil {
+		t.Fatalf("failed to create analyzer: %v", err)
+	}
+
+	pinned, err := a.Pinned(t.Context(), ref)
+	if err != nil {
+		t.Fatalf("failed to pin chart: %v", err)
+	}
+	if pinned.URL != ref.URL || !strings.HasPrefix(pinned.Version, "1.0.0@sha256:") {
+		t.Fatalf("expected the version to be pinned to 1.0.0 and its digest, got %+v", pinned)
+	}
+
+	// A version range cannot be resolved offline, but the pinned version is served from the cache
+	offline, err := New(WithCacheDir(cacheDir), WithLogger(discardLogger()), WithOffline(true))
+	if err != nil {
+		t.Fatalf("failed to create analyzer: %v", err)
+	}
+	if _, err := offline.Analyze(t.Context(), ref, Mutation{Path: "replicaCount"}); err == nil {
+		t.Error("expected the version range not to be found offline")
+	}
+	if _, err := offline.Analyze(t.Context(), pinned, Mutation{Path: "replicaCount"}); err != nil {
+		t.Errorf("failed to analyze the pinned chart offline: %v", err)
+	}
+}
+
+func TestAnalyzeRenderTimeout(t *testing.T) {
+	t.Parallel()
+
+	ref := serveTestChart(t)
+	a, err := New(WithCacheDir(t.TempDir()), WithLogger(discardLogger()), WithRenderTimeout(250*time.Millisecond))
+	if err != nil {
+		t.Fatalf("failed to create analyzer: %v", err)
+	}
+
+	_, err = a.Analyze(t.Context(), ref, Mutation{Path: "hang"})
+	var timeoutErr *RenderTimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Path != "hang" {
+		t.Fatalf("expected a render timeout for hang, got %v", err)
+	}
+
+	// The abandoned render does not block later analyses
+	if _, err := a.Analyze(t.Context(), ref, Mutation{Path: "replicaCount"}); err != nil {
+		t.Fatalf("failed to analyze after a render timeout: %v", err)
+	}
+}
+
+func TestAnalyzeIgnorePaths(t *testing.T) {
+	t.Parallel()
+
+	ref := serveTestChart(t)
+	a, err := New(WithCacheDir(t.TempDir()), WithLogger(discardLogger()), WithIgnorePaths([]string{"spec.replicas"}))
+	if err != nil {
+		t.Fatalf("failed to create analyzer: %v", err)
+	}
+
+	report, err := a.Analyze(t.Context(), ref, Mutation{Path: "replicaCount"})
+	if err != nil {
+		t.Fatalf("failed to analyze: %v", err)
+	}
+	if report.HasChanges() || report.Suppressed != 1 {
+		t.Errorf("expected the replicas change to be suppressed, got %+v with %d suppressed", report.Resources, report.Suppressed)
+	}
+
+	report, err = a.Analyze(t.Context(), ref, Mutation{Path: "service.port"})
+	if err != nil {
+		t.Fatalf("failed to analyze: %v", err)
+	}
+	if report.ChangeCount() != 1 || report.Suppressed != 0 {
+		t.Errorf("expected an unsuppressed port change, got %+v with %d suppressed", report.Resources, report.Suppressed)
+	}
+
+	if _, err := New(WithIgnorePaths([]string{"$.spec["})); err == nil {
+		t.Errorf("expected an invalid ignore path to be rejected")
+	}
+}
+
+func TestDiffResourcesSemantic(t *testing.T) {
+	t.Parallel()
+
+	baseline := map[string]interface{}{
+		"Deployment_web": map[string]interface{}{
+			"kind":     "Deployment",
+			"metadata": map[string]interface{}{"name": "web"},
+			"spec":     map[string]interface{}{"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "1000m"}}},
+		},
+	}
+	modified := map[string]interface{}{
+		"Deployment_web": map[string]interface{}{
+			"kind":     "Deployment",
+			"metadata": map[string]interface{}{"name": "web"},
+			"spec":     map[string]interface{}{"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "1"}}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		semantic  bool
+		wantCount int
+	}{
+		{name: "literal", semantic: false, wantCount: 1},
+		{name: "semantic", semantic: true, wantCount: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			resources, _ := diffResources(baseline, modified, yamldiff.CompareOptions{Semantic: tt.semantic}, nil)
+			if len(resources) != tt.wantCount {
+				t.Errorf("expected %d changed resources, got %+v", tt.wantCount, resources)
+			}
+		})
+	}
+}
+
+func TestDiffResourcesIgnoredPatches(t *testing.T) {
+	t.Parallel()
+
+	deployment := func(checksum string, replicas int) map[string]interface{} {
+		return map[string]interface{}{
+			"Deployment_web": map[string]interface{}{
+				"kind":     "Deployment",
+				"metadata": map[string]interface{}{"name": "web", "annotations": map[string]interface{}{"checksum/config": checksum}},
+				"spec":     map[string]interface{}{"replicas": replicas},
+			},
+		}
+	}
+	rules, err := yamldiff.NewIgnoreRules([]string{"metadata.annotations.checksum/*"})
+	if err != nil {
+		t.Fatalf("failed to compile rules: %v", err)
+	}
+
+	resources, suppressed := diffResources(deployment("abc", 1), deployment("def", 2), yamldiff.CompareOptions{}, rules)
+	if len(resources) != 1 || suppressed != 1 {
+		t.Fatalf("expected one resource and one suppressed difference, got %+v and %d", resources, suppressed)
+	}
+
+	for _, op := range resources[0].JSONPatch() {
+		if strings.HasPrefix(op.Path, "/metadata/annotations") {
+			t.Errorf("expected no JSON Patch operation on the ignored path, got %+v", op)
+		}
+	}
+	expected := map[string]interface{}{"spec": map[string]interface{}{"replicas": 2}}
+	if got := resources[0].MergePatch(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected merge patch %+v, got %+v", expected, got)
+	}
+}
+
+func TestDiffResourcesIgnoredListElements(t *testing.T) {
+	t.Parallel()
+
+	deployment := func(seed, port string) map[string]interface{} {
+		return map[string]interface{}{
+			"Deployment_web": map[string]interface{}{
+				"kind":     "Deployment",
+				"metadata": map[string]interface{}{"name": "web"},
+				"spec":     map[string]interface{}{"args": []interface{}{"--seed=" + seed, "--port=" + port}},
+			},
+		}
+	}
+	rules, err := yamldiff.NewIgnoreRules([]string{"spec.args[0]"})
+	if err != nil {
+		t.Fatalf("failed to compile rules: %v", err)
+	}
+
+	resources, suppressed := diffResources(deployment("1", "80"), deployment("2", "81"), yamldiff.CompareOptions{}, rules)
+	if len(resources) != 1 || suppressed != 1 {
+		t.Fatalf("expected one resource and one suppressed difference, got %+v and %d", resources, suppressed)
+	}
+
+	// The pointer of the second argument must stay valid against the rendered manifest
+	expected := []yamldiff.PatchOperation{{Op: "replace", Path: "/spec/args/1", Value: "--port=81"}}
+	if got := resources[0].JSONPatch(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected JSON Patch %+v, got %+v", expected, got)
+	}
+}
+
+func TestDiffResourcesPayloads(t *testing.T) {
+	t.Parallel()
+
+	secret := func(config string) map[string]interface{} {
+		return map[string]interface{}{
+			"Secret_web": map[string]interface{}{
+				"kind":     "Secret",
+				"metadata": map[string]interface{}{"name": "web"},
+				"data":     map[string]interface{}{"config.yaml": base64.StdEncoding.EncodeToString([]byte(config))},
+			},
+		}
+	}
+
+	resources, _ := diffResources(secret("user: admin\npassword: old\n"), secret("user: admin\npassword: new\n"), yamldiff.CompareOptions{Payloads: true}, nil)
+	if len(resources) != 1 || len(resources[0].Changes) != 1 {
+		t.Fatalf("expected a single change, got %+v", resources)
+	}
+	change := resources[0].Changes[0]
+	if change.Path != "Secret_web.data.config.yaml.password" {
+		t.Errorf("expected the change inside the payload, got %s", change.Path)
+	}
+	if change.Before != "old" || change.After != "new" {
+		t.Errorf("expected decoded values, got %v and %v", change.Before, change.After)
+	}
+
+	redactResources(resources, yamldiff.NewRedactor(nil))
+	change = resources[0].Changes[0]
+	if change.Before != yamldiff.RedactedValue("old") || change.After != yamldiff.RedactedValue("new") {
+		t.Errorf("expected redacted values, got %v and %v", change.Before, change.After)
+	}
+	data := resources[0].After.(map[string]interface{})["data"].(map[string]interface{})
+	if data["config.yaml"] != yamldiff.RedactedValue(base64.StdEncoding.EncodeToString([]byte("user: admin\npassword: new\n"))) {
+		t.Errorf("expected the Secret data to be redacted, got %v", data)
+	}
+}
@@ -0,0 +1,108 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Drumato/helmhound/pkg/helmwrap"
+	"github.com/Drumato/helmhound/pkg/yamldiff"
+	"gopkg.in/yaml.v3"
+)
+
+// indexSuffixPattern matches the list indices at the end of a value path, e.g. "[0]" in "auth.tokens[0]"
+var indexSuffixPattern = regexp.MustCompile(`(\[\d+\])+$`)
+
+// newRedactor creates the redactor of a chart, which also redacts the string values of the sensitive value paths
+// in the chart defaults, the values file and the values of the baseline release. It returns nil if redaction is disabled.
+func (a *Analyzer) newRedactor(values []helmwrap.ValueInfo) (*yamldiff.Redactor, error) {
+	if a.revealSecrets {
+		return nil, nil
+	}
+
+	infos := values
+	if a.valuesFile != "" {
+		content, err := os.ReadFile(a.valuesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read values file: %v", err)
+		}
+		fileValues, err := helmwrap.DescribeValuePaths(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse values file: %v", err)
+		}
+		infos = append(append([]helmwrap.ValueInfo{}, infos...), fileValues...)
+	}
+	if releaseValues := a.client.ReleaseValues(); releaseValues != nil {
+		// The values of the deployed release are the baseline, so their credentials end up in the manifests too
+		content, err := yaml.Marshal(releaseValues)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode release values: %v", err)
+		}
+		releaseInfos, err := helmwrap.DescribeValuePaths(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse release values: %v", err)
+		}
+		infos = append(append([]helmwrap.ValueInfo{}, infos...), releaseInfos...)
+	}
+
+	var sensitiveValues []string
+	for _, info := range infos {
+		if value, ok := info.Default.(string); ok && sensitiveValuePath(info.Path, a.sensitivePaths) {
+			sensitiveValues = append(sensitiveValues, value)
+		}
+	}
+	return yamldiff.NewRedactor(sensitiveValues), nil
+}
+
+// sensitiveValuePath reports whether a value path is one of the sensitive paths or below one,
+// or whether its key is named like a credential
+func sensitiveValuePath(path string, sensitivePaths []string) bool {
+	for _, sensitive := range sensitivePaths {
+		if path == sensitive || strings.HasPrefix(path, sensitive+".") || strings.HasPrefix(path, sensitive+"[") {
+			return true
+		}
+	}
+
+	key := indexSuffixPattern.ReplaceAllString(path, "")
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		key = key[i+1:]
+	}
+	return yamldiff.IsSensitiveKey(key)
+}
+
+// redactValues returns copies of the values with the defaults of sensitive value paths redacted
+func redactValues(values []helmwrap.ValueInfo, redactor *yamldiff.Redactor, sensitivePaths []string) []helmwrap.ValueInfo {
+	if redactor == nil {
+		return values
+	}
+
+	redacted := make([]helmwrap.ValueInfo, len(values))
+	for i, info := range values {
+		if sensitiveValuePath(info.Path, sensitivePaths) {
+			info.Default = yamldiff.RedactLeaves(info.Default)
+		} else {
+			info.Default = redactor.Redact(info.Default)
+		}
+		redacted[i] = info
+	}
+	return redacted
+}
+
+// redactResources redacts the manifests and changes of the resources in place
+func redactResources(resources []ResourceDiff, redactor *yamldiff.Redactor) {
+	if redactor == nil {
+		return
+	}
+
+	for i := range resources {
+		resource := &resources[i]
+		for j := range resource.Changes {
+			change := &resource.Changes[j]
+			change.Before = redactor.RedactAt(resource.Before, change.Pointer, change.Before)
+			change.After = redactor.RedactAt(resource.After, change.Pointer, change.After)
+		}
+		resource.Before = redactor.Redact(resource.Before)
+		resource.After = redactor.Redact(resource.After)
+	}
+}
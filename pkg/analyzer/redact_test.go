@@ -0,0 +1,103 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Drumato/helmhound/pkg/helmwrap"
+	"github.com/Drumato/helmhound/pkg/yamldiff"
+)
+
+func TestSensitiveValuePath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		path           string
+		sensitivePaths []string
+		expected       bool
+	}{
+		{name: "key named like a credential", path: "postgresql.auth.password", expected: true},
+		{name: "list element of such a key", path: "auth.tokens[1]", expected: true},
+		{name: "reference to a credential", path: "auth.existingSecret", expected: false},
+		{name: "listed path", path: "license", sensitivePaths: []string{"license"}, expected: true},
+		{name: "below a listed path", path: "smtp.user", sensitivePaths: []string{"smtp"}, expected: true},
+		{name: "listed path is not a prefix of other keys", path: "smtpHost", sensitivePaths: []string{"smtp"}, expected: false},
+		{name: "ordinary value", path: "image.tag", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := sensitiveValuePath(tt.path, tt.sensitivePaths); got != tt.expected {
+				t.Errorf("sensitiveValuePath(%q) = %v, want %v", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRedactValues(t *testing.T) {
+	t.Parallel()
+
+	values := []helmwrap.ValueInfo{
+		{Path: "auth", Type: helmwrap.ValueTypeMap, Default: map[string]interface{}{"user": "admin", "password": "hunter22"}},
+		{Path: "auth.password", Type: helmwrap.ValueTypeString, Default: "hunter22"},
+		{Path: "license", Type: helmwrap.ValueTypeInt, Default: 1234},
+		{Path: "replicaCount", Type: helmwrap.ValueTypeInt, Default: 1},
+	}
+	redactor := yamldiff.NewRedactor([]string{"hunter22"})
+
+	expected := []helmwrap.ValueInfo{
+		{Path: "auth", Type: helmwrap.ValueTypeMap, Default: map[string]interface{}{"user": "admin", "password": yamldiff.RedactedValue("hunter22")}},
+		{Path: "auth.password", Type: helmwrap.ValueTypeString, Default: yamldiff.RedactedValue("hunter22")},
+		{Path: "license", Type: helmwrap.ValueTypeInt, Default: yamldiff.RedactedValue(1234)},
+		{Path: "replicaCount", Type: helmwrap.ValueTypeInt, Default: 1},
+	}
+	if got := redactValues(values, redactor, []string{"license"}); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+	if values[1].Default != "hunter22" {
+		t.Errorf("expected the original values to be kept, got %v", values[1].Default)
+	}
+	if got := redactValues(values, nil, nil); !reflect.DeepEqual(got, values) {
+		t.Errorf("expected no redaction without a redactor, got %+v", got)
+	}
+}
+
+// releaseClient is a client comparing against a deployed release with the given values
+type releaseClient struct {
+	helmwrap.Client
+	values map[string]interface{}
+}
+
+func (c *releaseClient) ReleaseValues() map[string]interface{} {
+	return c.values
+}
+
+func TestNewRedactorFromRelease(t *testing.T) {
+	t.Parallel()
+
+	// The release was deployed with a password that neither the chart defaults nor the values file contain
+	a := &Analyzer{client: &releaseClient{values: map[string]interface{}{
+		"postgresql": map[string]interface{}{"auth": map[string]interface{}{"password": "pr0d-passw0rd"}},
+	}}}
+	redactor, err := a.newRedactor([]helmwrap.ValueInfo{
+		{Path: "postgresql.auth.password", Type: helmwrap.ValueTypeString, Default: "changeme"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create redactor: %v", err)
+	}
+
+	configMap := map[string]interface{}{
+		"kind": "ConfigMap",
+		"data": map[string]interface{}{"DATABASE_URL": "postgres://app:pr0d-passw0rd@db:5432/app"},
+	}
+	expected := map[string]interface{}{
+		"kind": "ConfigMap",
+		"data": map[string]interface{}{"DATABASE_URL": yamldiff.RedactedValue("postgres://app:pr0d-passw0rd@db:5432/app")},
+	}
+	if got := redactor.Redact(configMap); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
@@ -0,0 +1,150 @@
+package analyzer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Drumato/helmhound/pkg/helmwrap"
+	"github.com/Drumato/helmhound/pkg/yamldiff"
+)
+
+// Report is the result of analyzing a mutation
+type Report struct {
+	Chart    ChartRef `json:"chart"`
+	Mutation Mutation `json:"mutation"`
+	// Value describes the mutated value in the chart's values.yaml; it is nil if values.yaml does not define it
+	Value *helmwrap.ValueInfo `json:"value,omitempty"`
+	// Applied describes how the value was modified; it is nil if the value could not be modified
+	Applied *AppliedMutation `json:"applied,omitempty"`
+	// Resources are the changed resources ordered by kind and name
+	Resources []ResourceDiff `json:"resources"`
+	// Suppressed is the number of differences the ignore rules removed from Resources
+	Suppressed int `json:"suppressed"`
+	// RenderError is set instead of Resources when the chart fails to render with the mutation applied
+	RenderError *helmwrap.RenderError `json:"renderError,omitempty"`
+}
+
+// AppliedMutation describes how the mutated value was modified and how the values of the chart changed with it
+type AppliedMutation struct {
+	Strategy helmwrap.MutationStrategy `json:"strategy"`
+	// Original and Modified are the value before and after the modification
+	Original interface{} `json:"original"`
+	Modified interface{} `json:"modified"`
+	// ValueChanges are the differences between the baseline and modified values ordered by their JSON Pointer.
+	// The values include subchart defaults and the globals Helm propagates to subcharts, so a modified global
+	// also shows up in the values of each subchart.
+	ValueChanges []Change `json:"valueChanges"`
+}
+
+// ResourceDiff lists the changes of one rendered resource
+type ResourceDiff struct {
+	// Key identifies the resource in the rendered manifests, e.g. "Deployment_web"
+	Key  string `json:"key"`
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Type is DiffTypeAdded or DiffTypeRemoved when the whole resource appeared or vanished, otherwise DiffTypeModified
+	Type yamldiff.DiffType `json:"type"`
+	// Changes are ordered by path
+	Changes []Change `json:"changes"`
+	// Before and After are the rendered manifests of the resource; Before is nil for added resources and After for removed ones
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// Change is a difference at one path of a resource
+type Change struct {
+	// Path is the changed path including the resource key, e.g. "Deployment_web.spec.replicas".
+	// It equals the resource key when the whole resource was added or removed.
+	Path string `json:"path"`
+	// Pointer is the RFC 6901 JSON Pointer of the change, starting with the resource key
+	Pointer string            `json:"pointer"`
+	Type    yamldiff.DiffType `json:"type"`
+	Before  interface{}       `json:"before,omitempty"`
+	After   interface{}       `json:"after,omitempty"`
+}
+
+// JSONPatch returns the RFC 6902 JSON Patch that turns the Before manifest of a modified resource into its After manifest.
+// It returns nil for added and removed resources.
+func (r ResourceDiff) JSONPatch() []yamldiff.PatchOperation {
+	before, after, ok := r.manifests()
+	if !ok {
+		return nil
+	}
+	return yamldiff.JSONPatch(before, after)
+}
+
+// MergePatch returns the RFC 7386 merge patch that turns the Before manifest of a modified resource into its After manifest.
+// It returns nil for added and removed resources.
+func (r ResourceDiff) MergePatch() map[string]interface{} {
+	before, after, ok := r.manifests()
+	if !ok {
+		return nil
+	}
+	return yamldiff.MergePatch(before, after)
+}
+
+// manifests returns the Before and After manifests if both are mappings
+func (r ResourceDiff) manifests() (map[string]interface{}, map[string]interface{}, bool) {
+	before, okBefore := r.Before.(map[string]interface{})
+	after, okAfter := r.After.(map[string]interface{})
+	return before, after, okBefore && okAfter
+}
+
+// HasChanges reports whether the mutation changed any rendered resource
+func (r *Report) HasChanges() bool {
+	return len(r.Resources) > 0
+}
+
+// Failed reports whether the chart failed to render with the mutation applied
+func (r *Report) Failed() bool {
+	return r.RenderError != nil
+}
+
+// ResourcesByType returns the changed resources of the given type ordered by kind and name
+func (r *Report) ResourcesByType(diffType yamldiff.DiffType) []ResourceDiff {
+	var resources []ResourceDiff
+	for _, resource := range r.Resources {
+		if resource.Type == diffType {
+			resources = append(resources, resource)
+		}
+	}
+	return resources
+}
+
+// ChangeCount returns the number of changed paths over all resources
+func (r *Report) ChangeCount() int {
+	count := 0
+	for _, resource := range r.Resources {
+		count += len(resource.Changes)
+	}
+	return count
+}
+
+// RenderTimeoutError reports a render that did not finish within the render timeout
+type RenderTimeoutError struct {
+	// Path is the mutated value path, or empty for the baseline render
+	Path    string
+	Timeout time.Duration
+}
+
+func (e *RenderTimeoutError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("rendering the original template did not finish within %s", e.Timeout)
+	}
+	return fmt.Sprintf("rendering with %s modified did not finish within %s", e.Path, e.Timeout)
+}
+
+// MutationError reports a value path that could not be modified or rendered, e.g. because it does not exist
+type MutationError struct {
+	// Path is the mutated value path
+	Path string
+	Err  error
+}
+
+func (e *MutationError) Error() string {
+	return fmt.Sprintf("failed to render template with %s modified: %v", e.Path, e.Err)
+}
+
+func (e *MutationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,325 @@
+package helmwrap
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExportCache writes the cached charts and their cache.yaml entries to w as a gzipped tarball.
+// If chartUrls is not empty, only entries for those chart URLs are exported.
+func ExportCache(helmhoundDir string, w io.Writer, chartUrls []string) ([]CacheEntry, error) {
+	// Hold the lock so that a concurrent prune cannot delete a chart while it is being archived
+	lock, err := lockCache(helmhoundDir)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	var exported []CacheEntry
+	for _, entry := range loadCacheFile(helmhoundDir).Entries {
+		if len(chartUrls) > 0 && !slices.Contains(chartUrls, entry.ChartURL) {
+			continue
+		}
+		if _, err := os.Stat(entry.ChartPath()); err != nil {
+			return nil, fmt.Errorf("cached chart %s %s is missing, run 'cache verify' first", entry.ChartURL, entry.Version)
+		}
+		exported = append(exported, entry)
+	}
+	if len(exported) == 0 {
+		return nil, fmt.Errorf("no cached charts to export")
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	// Charts are flattened into the root of the bundle, which cache.yaml refers to as "."
+	bundleFile := CacheFile{}
+	written := make(map[string]bool)
+	for _, entry := range exported {
+		bundleEntry := entry
+		bundleEntry.DownloadDir = "."
+		bundleFile.Entries = append(bundleFile.Entries, bundleEntry)
+
+		if written[entry.ChartName] {
+			continue
+		}
+		written[entry.ChartName] = true
+		if err := addDirToTar(tw, entry.ChartPath(), entry.ChartName); err != nil {
+			return nil, fmt.Errorf("failed to archive chart %s: %v", entry.ChartName, err)
+		}
+	}
+
+	content, err := yaml.Marshal(bundleFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal cache file: %v", err)
+	}
+	if err := writeTarFile(tw, "cache.yaml", content, 0644); err != nil {
+		return nil, fmt.Errorf("failed to archive cache.yaml: %v", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish cache bundle: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish cache bundle: %v", err)
+	}
+
+	return exported, nil
+}
+
+// ImportCache copies cached charts from src, a cache directory or a bundle written by ExportCache,
+// into helmhoundDir and records them in its cache.yaml with download directories rewritten for this host.
+// Cleanup failures that do not fail the import are logged to logger.
+func ImportCache(helmhoundDir, src string, logger *slog.Logger) ([]CacheEntry, error) {
+	if err := os.MkdirAll(helmhoundDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create helmhound directory: %v", err)
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import source: %v", err)
+	}
+	if destInfo, err := os.Stat(helmhoundDir); err == nil && os.SameFile(info, destInfo) {
+		return nil, fmt.Errorf("cannot import the cache directory %s into itself", helmhoundDir)
+	}
+
+	// Stage the charts next to the cache so that they can be moved into place with a rename
+	stagingDir, err := os.MkdirTemp(helmhoundDir, ".import-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create import directory: %v", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(stagingDir); err != nil {
+			logger.Warn("failed to clean up import directory", "error", err)
+		}
+	}()
+
+	if info.IsDir() {
+		err = stageCacheDir(src, stagingDir)
+	} else {
+		err = extractBundle(src, stagingDir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack %s: %v", src, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(stagingDir, "cache.yaml")); err != nil {
+		return nil, fmt.Errorf("cache.yaml not found in %s", src)
+	}
+
+	lock, err := lockCache(helmhoundDir)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	// Charts moved into the cache are moved back into the staging directory if the import fails,
+	// so that no chart directory is left without an entry in cache.yaml
+	var moved []string
+	rollback := func() {
+		for _, chartPath := range moved {
+			if err := os.Rename(chartPath, filepath.Join(stagingDir, filepath.Base(chartPath))); err != nil {
+				logger.Warn("failed to roll back imported chart", "path", chartPath, "error", err)
+			}
+		}
+	}
+
+	cacheFile := loadCacheFile(helmhoundDir)
+	var imported, stale []CacheEntry
+	for _, entry := range loadCacheFile(stagingDir).Entries {
+		if !isPlainName(entry.ChartName) {
+			rollback()
+			return nil, fmt.Errorf("invalid chart directory name %q in %s", entry.ChartName, src)
+		}
+
+		// Download directories recorded on another host are meaningless here; charts live at the root of src
+		stagedChartPath := filepath.Join(stagingDir, entry.ChartName)
+		if _, err := os.Stat(stagedChartPath); err != nil {
+			rollback()
+			return nil, fmt.Errorf("chart directory %s listed in cache.yaml is missing from %s", entry.ChartName, src)
+		}
+
+		entry.DownloadDir = helmhoundDir
+		if _, err := os.Stat(entry.ChartPath()); os.IsNotExist(err) {
+			if err := os.Rename(stagedChartPath, entry.ChartPath()); err != nil {
+				rollback()
+				return nil, fmt.Errorf("failed to import chart %s: %v", entry.ChartName, err)
+			}
+			moved = append(moved, entry.ChartPath())
+		}
+
+		stale = append(stale, addCacheEntry(&cacheFile, entry)...)
+		imported = append(imported, entry)
+	}
+	if err := saveCacheFile(helmhoundDir, cacheFile); err != nil {
+		rollback()
+		return nil, err
+	}
+	// Replaced charts are only removed once cache.yaml no longer refers to them
+	removeStaleCharts(logger, helmhoundDir, cacheFile, stale)
+
+	return imported, nil
+}
+
+// stageCacheDir copies cache.yaml and the chart directories it lists from the cache directory src into dest
+func stageCacheDir(src, dest string) error {
+	content, err := os.ReadFile(filepath.Join(src, "cache.yaml"))
+	if err != nil {
+		return fmt.Errorf("cache.yaml not found in %s", src)
+	}
+	if err := os.WriteFile(filepath.Join(dest, "cache.yaml"), content, 0644); err != nil {
+		return err
+	}
+
+	for _, entry := range loadCacheFile(src).Entries {
+		if !isPlainName(entry.ChartName) {
+			continue // rejected when the staged entries are imported
+		}
+		srcChartPath := filepath.Join(src, entry.ChartName)
+		if _, err := os.Stat(srcChartPath); err != nil {
+			continue // reported as missing when the staged entries are imported
+		}
+		if err := copyDir(srcChartPath, filepath.Join(dest, entry.ChartName)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// extractBundle unpacks a gzipped tarball into dest, rejecting entries that would escape it
+func extractBundle(bundlePath, dest string) error {
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return fmt.Errorf("failed to open bundle: %v", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to read bundle %s: %v", bundlePath, err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read bundle %s: %v", bundlePath, err)
+		}
+
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("invalid path %q in bundle", header.Name)
+		}
+		target := filepath.Join(dest, filepath.FromSlash(name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				_ = out.Close()
+				return err
+			}
+			if err := out.Close(); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported entry %q in bundle", header.Name)
+		}
+	}
+}
+
+// addDirToTar archives the regular files and directories under dir with the given name prefix
+func addDirToTar(tw *tar.Writer, dir, prefix string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		name := path.Join(prefix, filepath.ToSlash(rel))
+
+		if d.IsDir() {
+			return tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: name + "/", Mode: 0755})
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return writeTarFile(tw, name, content, 0644)
+	})
+}
+
+// writeTarFile writes a single regular file to tw
+func writeTarFile(tw *tar.Writer, name string, content []byte, mode int64) error {
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: mode, Size: int64(len(content))}); err != nil {
+		return err
+	}
+	_, err := tw.Write(content)
+	return err
+}
+
+// copyDir recursively copies the regular files and directories under src to dst
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, 0644)
+	})
+}
+
+// isPlainName reports whether name is a single path element
+func isPlainName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
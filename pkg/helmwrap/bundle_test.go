@@ -0,0 +1,218 @@
+package helmwrap
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportImportCache(t *testing.T) {
+	t.Parallel()
+
+	srcDir := t.TempDir()
+	writeCachedChart(t, srcDir, "oci://example.com/charts/app", "1.0.0", time.Now(), 10)
+	writeCachedChart(t, srcDir, "oci://example.com/charts/other", "2.0.0", time.Now(), 10)
+
+	tests := []struct {
+		name      string
+		chartUrls []string
+		wantURLs  []string
+	}{
+		{
+			name:     "all charts",
+			wantURLs: []string{"oci://example.com/charts/app", "oci://example.com/charts/other"},
+		},
+		{
+			name:      "selected charts",
+			chartUrls: []string{"oci://example.com/charts/other"},
+			wantURLs:  []string{"oci://example.com/charts/other"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			bundlePath := filepath.Join(t.TempDir(), "bundle.tgz")
+			bundle, err := os.Create(bundlePath)
+			if err != nil {
+				t.Fatalf("failed to create bundle: %v", err)
+			}
+			if _, err := ExportCache(srcDir, bundle, tt.chartUrls); err != nil {
+				t.Fatalf("failed to export cache: %v", err)
+			}
+			if err := bundle.Close(); err != nil {
+				t.Fatalf("failed to close bundle: %v", err)
+			}
+
+			destDir := filepath.Join(t.TempDir(), "cache")
+			imported, err := ImportCache(destDir, bundlePath, slog.New(slog.DiscardHandler))
+			if err != nil {
+				t.Fatalf("failed to import cache: %v", err)
+			}
+			if len(imported) != len(tt.wantURLs) {
+				t.Fatalf("expected %d imported entries, got %+v", len(tt.wantURLs), imported)
+			}
+
+			entries := ListCacheEntries(destDir)
+			for i, entry := range entries {
+				if entry.ChartURL != tt.wantURLs[i] {
+					t.Errorf("expected %s, got %s", tt.wantURLs[i], entry.ChartURL)
+				}
+				if entry.DownloadDir != destDir {
+					t.Errorf("expected download dir to be rewritten to %s, got %s", destDir, entry.DownloadDir)
+				}
+				if _, err := readChartVersion(entry.ChartPath()); err != nil {
+					t.Errorf("imported chart %s is not readable: %v", entry.ChartName, err)
+				}
+			}
+
+			if _, ok := checkCacheEntry(destDir, tt.wantURLs[0], entries[0].Version, ""); !ok {
+				t.Error("expected imported chart to be served from the cache")
+			}
+		})
+	}
+}
+
+func TestImportCacheFromCopiedDirectory(t *testing.T) {
+	t.Parallel()
+
+	// A cache copied from another host, written by an older version with absolute download directories
+	srcDir := t.TempDir()
+	entry := writeCachedChart(t, srcDir, "oci://example.com/charts/app", "1.0.0", time.Now(), 10)
+	cacheYaml := "entries:\n" +
+		"  - chart_url: " + entry.ChartURL + "\n" +
+		"    version: " + entry.Version + "\n" +
+		"    chart_name: " + entry.ChartName + "\n" +
+		"    download_dir: /home/someone-else/.helmhound\n"
+	if err := os.WriteFile(filepath.Join(srcDir, "cache.yaml"), []byte(cacheYaml), 0644); err != nil {
+		t.Fatalf("failed to write cache.yaml: %v", err)
+	}
+
+	destDir := t.TempDir()
+	if _, err := ImportCache(destDir, srcDir, slog.New(slog.DiscardHandler)); err != nil {
+		t.Fatalf("failed to import cache: %v", err)
+	}
+
+	found, ok := checkCacheEntry(destDir, entry.ChartURL, entry.Version, "")
+	if !ok {
+		t.Fatal("expected imported chart to be served from the cache")
+	}
+	if found.DownloadDir != destDir {
+		t.Errorf("expected download dir %s, got %s", destDir, found.DownloadDir)
+	}
+
+	content, err := os.ReadFile(filepath.Join(destDir, "cache.yaml"))
+	if err != nil {
+		t.Fatalf("failed to read cache.yaml: %v", err)
+	}
+	if strings.Contains(string(content), destDir) {
+		t.Errorf("expected cache.yaml to store relative download directories, got:\n%s", content)
+	}
+
+	if _, err := os.Stat(entry.ChartPath()); err != nil {
+		t.Errorf("importing from a directory must not modify the source: %v", err)
+	}
+}
+
+func TestImportCacheRollsBackOnError(t *testing.T) {
+	t.Parallel()
+
+	// The first chart is moved into the cache before the second one turns out to be missing
+	srcDir := t.TempDir()
+	entry := writeCachedChart(t, srcDir, "oci://example.com/charts/app", "1.0.0", time.Now(), 10)
+	cacheYaml := "entries:\n" +
+		"  - chart_url: " + entry.ChartURL + "\n" +
+		"    version: " + entry.Version + "\n" +
+		"    chart_name: " + entry.ChartName + "\n" +
+		"  - chart_url: oci://example.com/charts/missing\n" +
+		"    version: 1.0.0\n" +
+		"    chart_name: missing-1.0.0\n"
+	if err := os.WriteFile(filepath.Join(srcDir, "cache.yaml"), []byte(cacheYaml), 0644); err != nil {
+		t.Fatalf("failed to write cache.yaml: %v", err)
+	}
+
+	destDir := t.TempDir()
+	if _, err := ImportCache(destDir, srcDir, slog.New(slog.DiscardHandler)); err == nil {
+		t.Fatal("expected error for a chart missing from the source")
+	}
+
+	if _, err := os.Stat(filepath.Join(destDir, entry.ChartName)); !os.IsNotExist(err) {
+		t.Errorf("expected the moved chart directory to be rolled back, got %v", err)
+	}
+	if entries := loadCacheFile(destDir).Entries; len(entries) != 0 {
+		t.Errorf("expected no cache entries, got %+v", entries)
+	}
+}
+
+func TestImportCacheKeepsReplacedChartsOnError(t *testing.T) {
+	t.Parallel()
+
+	// The cached chart is replaced by a rebuilt chart of the same version before the second chart turns out to be missing
+	destDir := t.TempDir()
+	cached := writeCachedChart(t, destDir, "oci://example.com/charts/app", "1.0.0", time.Now(), 10)
+
+	srcDir := t.TempDir()
+	rebuilt := writeCachedChart(t, srcDir, "oci://example.com/charts/app", "1.0.0", time.Now(), 20)
+	rebuiltName := rebuilt.ChartName + "-0123abcd"
+	if err := os.Rename(rebuilt.ChartPath(), filepath.Join(srcDir, rebuiltName)); err != nil {
+		t.Fatalf("failed to rename chart: %v", err)
+	}
+	cacheYaml := "entries:\n" +
+		"  - chart_url: " + rebuilt.ChartURL + "\n" +
+		"    version: " + rebuilt.Version + "\n" +
+		"    chart_name: " + rebuiltName + "\n" +
+		"  - chart_url: oci://example.com/charts/missing\n" +
+		"    version: 1.0.0\n" +
+		"    chart_name: missing-1.0.0\n"
+	if err := os.WriteFile(filepath.Join(srcDir, "cache.yaml"), []byte(cacheYaml), 0644); err != nil {
+		t.Fatalf("failed to write cache.yaml: %v", err)
+	}
+
+	if _, err := ImportCache(destDir, srcDir, slog.New(slog.DiscardHandler)); err == nil {
+		t.Fatal("expected error for a chart missing from the source")
+	}
+
+	if _, err := os.Stat(cached.ChartPath()); err != nil {
+		t.Errorf("expected the cached chart to be kept, got %v", err)
+	}
+	if _, ok := checkCacheEntry(destDir, cached.ChartURL, cached.Version, ""); !ok {
+		t.Error("expected the cached chart to still be served from the cache")
+	}
+}
+
+func TestImportCacheRejectsUnsafeBundle(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := writeTarFile(tw, "../escape.txt", []byte("boom"), 0644); err != nil {
+		t.Fatalf("failed to write tar: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close tar: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("failed to close gzip: %v", err)
+	}
+
+	bundlePath := filepath.Join(t.TempDir(), "evil.tgz")
+	if err := os.WriteFile(bundlePath, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("failed to write bundle: %v", err)
+	}
+
+	destDir := filepath.Join(t.TempDir(), "cache")
+	if _, err := ImportCache(destDir, bundlePath, slog.New(slog.DiscardHandler)); err == nil {
+		t.Fatal("expected error for bundle entry escaping the destination")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(destDir), "escape.txt")); !os.IsNotExist(err) {
+		t.Error("bundle entry must not be written outside of the cache")
+	}
+}
@@ -0,0 +1,415 @@
+package helmwrap
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CacheEntry represents a single cache entry
+type CacheEntry struct {
+	ChartURL    string    `yaml:"chart_url"`
+	Version     string    `yaml:"version"`
+	ChartName   string    `yaml:"chart_name"`
+	DownloadDir string    `yaml:"download_dir"`
+	Size        int64     `yaml:"size,omitempty"`
+	LastUsed    time.Time `yaml:"last_used,omitempty"`
+	// Digest is the sha256 digest of the chart archive
+	Digest string `yaml:"digest,omitempty"`
+	// ManifestDigest is the OCI manifest digest for charts pulled from a registry
+	ManifestDigest string `yaml:"manifest_digest,omitempty"`
+}
+
+// ChartPath returns the directory the cached chart was extracted to
+func (e CacheEntry) ChartPath() string {
+	return filepath.Join(e.DownloadDir, e.ChartName)
+}
+
+// matches reports whether the entry satisfies a request for chartUrl at version, or at digest when pinned
+func (e CacheEntry) matches(chartUrl, version, digest string) bool {
+	if e.ChartURL != chartUrl {
+		return false
+	}
+	if digest != "" {
+		return e.Digest == digest || e.ManifestDigest == digest
+	}
+	return e.Version == version
+}
+
+// CacheFile represents the structure of cache.yaml
+type CacheFile struct {
+	Entries []CacheEntry `yaml:"entries"`
+}
+
+// PruneOptions controls which entries PruneCache removes
+type PruneOptions struct {
+	// OlderThan removes entries that have not been used within the duration (0 disables)
+	OlderThan time.Duration
+	// MaxSize removes least recently used entries until the cache fits (0 disables)
+	MaxSize int64
+}
+
+// VerifyResult describes a cache entry that failed verification and was dropped
+type VerifyResult struct {
+	Entry   CacheEntry
+	Problem string
+}
+
+// DefaultCacheDir returns the directory helmhound stores downloaded charts in
+func DefaultCacheDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %v", err)
+	}
+	return filepath.Join(homeDir, ".helmhound"), nil
+}
+
+// ListCacheEntries returns all cache entries sorted by chart URL and version
+func ListCacheEntries(helmhoundDir string) []CacheEntry {
+	cacheFile := loadCacheFile(helmhoundDir)
+
+	entries := make([]CacheEntry, 0, len(cacheFile.Entries))
+	for _, entry := range cacheFile.Entries {
+		// Entries written by older versions do not record their size
+		if entry.Size == 0 {
+			entry.Size = dirSize(entry.ChartPath())
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].ChartURL != entries[j].ChartURL {
+			return entries[i].ChartURL < entries[j].ChartURL
+		}
+		return entries[i].Version < entries[j].Version
+	})
+
+	return entries
+}
+
+// RemoveCacheEntry removes the entry for the given chart URL and version along with its chart directory
+func RemoveCacheEntry(helmhoundDir, chartUrl, version string) (CacheEntry, error) {
+	var removed CacheEntry
+	err := updateCacheFile(helmhoundDir, func(cacheFile *CacheFile) error {
+		for i, entry := range cacheFile.Entries {
+			if entry.ChartURL == chartUrl && entry.Version == version {
+				cacheFile.Entries = append(cacheFile.Entries[:i], cacheFile.Entries[i+1:]...)
+				removed = entry
+				return removeChartDir(helmhoundDir, *cacheFile, entry)
+			}
+		}
+		return fmt.Errorf("chart %s version %s not found in cache", chartUrl, version)
+	})
+	if err != nil {
+		return CacheEntry{}, err
+	}
+
+	return removed, nil
+}
+
+// PruneCache removes stale entries and evicts least recently used entries until the cache fits in MaxSize
+func PruneCache(helmhoundDir string, opts PruneOptions, now time.Time) ([]CacheEntry, error) {
+	var pruned []CacheEntry
+	err := updateCacheFile(helmhoundDir, func(cacheFile *CacheFile) error {
+		var err error
+		pruned, err = pruneCacheFile(helmhoundDir, cacheFile, opts, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pruned, nil
+}
+
+// pruneCacheFile removes pruned entries from cacheFile, deletes their chart directories and returns them
+func pruneCacheFile(helmhoundDir string, cacheFile *CacheFile, opts PruneOptions, now time.Time) ([]CacheEntry, error) {
+	entries := make([]CacheEntry, 0, len(cacheFile.Entries))
+	for _, entry := range cacheFile.Entries {
+		if entry.Size == 0 {
+			entry.Size = dirSize(entry.ChartPath())
+		}
+		if entry.LastUsed.IsZero() {
+			entry.LastUsed = dirModTime(entry.ChartPath())
+		}
+		entries = append(entries, entry)
+	}
+
+	// Least recently used first, so eviction by size can pop from the front
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].LastUsed.Before(entries[j].LastUsed)
+	})
+
+	var kept, pruned []CacheEntry
+	for _, entry := range entries {
+		if opts.OlderThan > 0 && now.Sub(entry.LastUsed) > opts.OlderThan {
+			pruned = append(pruned, entry)
+		} else {
+			kept = append(kept, entry)
+		}
+	}
+
+	if opts.MaxSize > 0 {
+		var total int64
+		for _, entry := range kept {
+			total += entry.Size
+		}
+		for len(kept) > 0 && total > opts.MaxSize {
+			total -= kept[0].Size
+			pruned = append(pruned, kept[0])
+			kept = kept[1:]
+		}
+	}
+
+	cacheFile.Entries = kept
+	for _, entry := range pruned {
+		if err := removeChartDir(helmhoundDir, *cacheFile, entry); err != nil {
+			return nil, err
+		}
+	}
+
+	return pruned, nil
+}
+
+// VerifyCache re-checks every entry against its Chart.yaml and drops entries that no longer match
+func VerifyCache(helmhoundDir string) ([]VerifyResult, error) {
+	var dropped []VerifyResult
+	err := updateCacheFile(helmhoundDir, func(cacheFile *CacheFile) error {
+		dropped = verifyCacheFile(cacheFile)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dropped, nil
+}
+
+// verifyCacheFile drops entries from cacheFile whose chart directory does not match and reports them
+func verifyCacheFile(cacheFile *CacheFile) []VerifyResult {
+	var kept []CacheEntry
+	var dropped []VerifyResult
+	for _, entry := range cacheFile.Entries {
+		chartPath := entry.ChartPath()
+		if _, err := os.Stat(chartPath); err != nil {
+			dropped = append(dropped, VerifyResult{Entry: entry, Problem: "chart directory is missing"})
+			continue
+		}
+
+		version, err := readChartVersion(chartPath)
+		if err != nil {
+			dropped = append(dropped, VerifyResult{Entry: entry, Problem: err.Error()})
+			continue
+		}
+		if version != entry.Version {
+			dropped = append(dropped, VerifyResult{
+				Entry:   entry,
+				Problem: fmt.Sprintf("Chart.yaml has version %s", version),
+			})
+			continue
+		}
+
+		kept = append(kept, entry)
+	}
+
+	cacheFile.Entries = kept
+
+	return dropped
+}
+
+// checkCacheEntry checks if a chart with given URL and version, or pinned digest, exists in cache.
+// cache.yaml is replaced atomically, so it is read without the cache lock; the last used time of the entry is
+// updated on a best-effort basis.
+func checkCacheEntry(helmhoundDir, chartUrl, chartVersion, digest string) (CacheEntry, bool) {
+	for _, entry := range loadCacheFile(helmhoundDir).Entries {
+		if entry.matches(chartUrl, chartVersion, digest) {
+			// Verify that the cached directory still exists
+			if _, err := os.Stat(entry.ChartPath()); err == nil {
+				touchCacheEntry(helmhoundDir, entry)
+				return entry, true
+			}
+		}
+	}
+
+	return CacheEntry{}, false
+}
+
+// touchCacheEntry records that entry has just been used. The update is skipped if another process holds the cache
+// lock or the cache directory is read-only, since the last used time only orders entries for pruning.
+func touchCacheEntry(helmhoundDir string, entry CacheEntry) {
+	lock, err := tryLockCache(helmhoundDir)
+	if err != nil || lock == nil {
+		return
+	}
+	defer lock.Unlock()
+
+	cacheFile := loadCacheFile(helmhoundDir)
+	for i, other := range cacheFile.Entries {
+		if other.ChartURL == entry.ChartURL && other.ChartName == entry.ChartName {
+			cacheFile.Entries[i].LastUsed = time.Now()
+		}
+	}
+	_ = saveCacheFile(helmhoundDir, cacheFile)
+}
+
+// addCacheEntry adds a new entry to the cache file, replacing any entry for the same chart URL and version.
+// It returns the replaced entries whose content has changed since they were cached; the caller removes their
+// chart directories with removeStaleCharts once the cache file has been saved.
+func addCacheEntry(cacheFile *CacheFile, newEntry CacheEntry) []CacheEntry {
+	newEntry.Size = dirSize(newEntry.ChartPath())
+	newEntry.LastUsed = time.Now()
+
+	var stale []CacheEntry
+	kept := cacheFile.Entries[:0]
+	for _, entry := range cacheFile.Entries {
+		if entry.ChartURL == newEntry.ChartURL && entry.Version == newEntry.Version {
+			if entry.ChartName != newEntry.ChartName {
+				stale = append(stale, entry)
+			}
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	cacheFile.Entries = append(kept, newEntry)
+
+	return stale
+}
+
+// removeStaleCharts deletes the chart directories of entries replaced in the saved cacheFile; the caller must hold
+// the cache lock. Failures are logged to logger.
+func removeStaleCharts(logger *slog.Logger, helmhoundDir string, cacheFile CacheFile, stale []CacheEntry) {
+	for _, entry := range stale {
+		if err := removeChartDir(helmhoundDir, cacheFile, entry); err != nil {
+			// The stale entry is already gone from cache.yaml; a leftover directory is harmless
+			logger.Warn("failed to remove stale chart", "error", err)
+		}
+	}
+}
+
+// removeChartDir deletes the chart directory of entry unless another remaining entry still refers to it
+func removeChartDir(helmhoundDir string, cacheFile CacheFile, entry CacheEntry) error {
+	chartPath := entry.ChartPath()
+	for _, other := range cacheFile.Entries {
+		if other.ChartPath() == chartPath {
+			return nil
+		}
+	}
+
+	// Never delete anything outside of the cache directory, whatever cache.yaml says
+	rel, err := filepath.Rel(helmhoundDir, chartPath)
+	if err != nil || entry.ChartName == "" || rel == "." || strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("refusing to remove %s: not inside cache directory %s", chartPath, helmhoundDir)
+	}
+
+	if err := os.RemoveAll(chartPath); err != nil {
+		return fmt.Errorf("failed to remove chart directory %s: %v", chartPath, err)
+	}
+	return nil
+}
+
+// dirSize returns the total size of regular files under path, or 0 if it cannot be read
+func dirSize(path string) int64 {
+	var size int64
+	_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// dirModTime returns the modification time of path, or the zero time if it cannot be read
+func dirModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// relativeCacheFile returns a copy of cacheFile whose download directories inside helmhoundDir are relative to it
+func relativeCacheFile(helmhoundDir string, cacheFile CacheFile) CacheFile {
+	relative := CacheFile{Entries: make([]CacheEntry, len(cacheFile.Entries))}
+	for i, entry := range cacheFile.Entries {
+		if rel, err := filepath.Rel(helmhoundDir, entry.DownloadDir); err == nil && !strings.HasPrefix(rel, "..") {
+			entry.DownloadDir = filepath.ToSlash(rel)
+		}
+		relative.Entries[i] = entry
+	}
+	return relative
+}
+
+// loadCacheFile loads the cache file or returns empty cache if file doesn't exist
+func loadCacheFile(helmhoundDir string) CacheFile {
+	cacheFilePath := filepath.Join(helmhoundDir, "cache.yaml")
+
+	// If cache file doesn't exist, return empty cache
+	if _, err := os.Stat(cacheFilePath); os.IsNotExist(err) {
+		return CacheFile{Entries: []CacheEntry{}}
+	}
+
+	// Read and parse cache file
+	content, err := os.ReadFile(cacheFilePath)
+	if err != nil {
+		return CacheFile{Entries: []CacheEntry{}}
+	}
+
+	var cacheFile CacheFile
+	if err := yaml.Unmarshal(content, &cacheFile); err != nil {
+		return CacheFile{Entries: []CacheEntry{}}
+	}
+
+	// Download directories are stored relative to the cache directory so a copied cache keeps working
+	for i, entry := range cacheFile.Entries {
+		if !filepath.IsAbs(entry.DownloadDir) {
+			cacheFile.Entries[i].DownloadDir = filepath.Join(helmhoundDir, entry.DownloadDir)
+		}
+	}
+
+	return cacheFile
+}
+
+// saveCacheFile atomically replaces cache.yaml so readers never observe a partially written file
+func saveCacheFile(helmhoundDir string, cacheFile CacheFile) error {
+	cacheFilePath := filepath.Join(helmhoundDir, "cache.yaml")
+
+	content, err := yaml.Marshal(relativeCacheFile(helmhoundDir, cacheFile))
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache file: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(helmhoundDir, ".cache-*.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary cache file: %v", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write cache file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache file: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write cache file: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), cacheFilePath); err != nil {
+		return fmt.Errorf("failed to replace cache file: %v", err)
+	}
+	return nil
+}
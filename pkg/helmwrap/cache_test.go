@@ -0,0 +1,272 @@
+package helmwrap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCachedChart creates a minimal chart directory inside helmhoundDir and returns its entry
+func writeCachedChart(t *testing.T, helmhoundDir, chartUrl, version string, lastUsed time.Time, size int) CacheEntry {
+	t.Helper()
+
+	chartName := filepath.Base(chartUrl) + "-" + version
+	chartDir := filepath.Join(helmhoundDir, chartName)
+	if err := os.MkdirAll(chartDir, 0755); err != nil {
+		t.Fatalf("failed to create chart dir: %v", err)
+	}
+	chartYaml := "apiVersion: v2\nname: " + filepath.Base(chartUrl) + "\nversion: " + version + "\n"
+	if err := os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte(chartYaml), 0644); err != nil {
+		t.Fatalf("failed to write Chart.yaml: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(chartDir, "values.yaml"), make([]byte, size), 0644); err != nil {
+		t.Fatalf("failed to write values.yaml: %v", err)
+	}
+
+	entry := CacheEntry{
+		ChartURL:    chartUrl,
+		Version:     version,
+		ChartName:   chartName,
+		DownloadDir: helmhoundDir,
+		Size:        dirSize(chartDir),
+		LastUsed:    lastUsed,
+	}
+
+	err := updateCacheFile(helmhoundDir, func(cacheFile *CacheFile) error {
+		cacheFile.Entries = append(cacheFile.Entries, entry)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to add cache entry: %v", err)
+	}
+
+	return entry
+}
+
+func TestListCacheEntries(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	now := time.Now()
+	writeCachedChart(t, dir, "oci://example.com/charts/zeta", "1.0.0", now, 10)
+	writeCachedChart(t, dir, "oci://example.com/charts/alpha", "2.0.0", now, 10)
+	writeCachedChart(t, dir, "oci://example.com/charts/alpha", "1.0.0", now, 10)
+
+	entries := ListCacheEntries(dir)
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+
+	want := []string{"alpha 1.0.0", "alpha 2.0.0", "zeta 1.0.0"}
+	for i, entry := range entries {
+		got := filepath.Base(entry.ChartURL) + " " + entry.Version
+		if got != want[i] {
+			t.Errorf("entry %d: expected %s, got %s", i, want[i], got)
+		}
+		if entry.Size == 0 {
+			t.Errorf("entry %d: expected non-zero size", i)
+		}
+	}
+}
+
+func TestRemoveCacheEntry(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	removed := writeCachedChart(t, dir, "oci://example.com/charts/app", "1.0.0", time.Now(), 10)
+	writeCachedChart(t, dir, "oci://example.com/charts/app", "2.0.0", time.Now(), 10)
+
+	if _, err := RemoveCacheEntry(dir, "oci://example.com/charts/app", "1.0.0"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat(removed.ChartPath()); !os.IsNotExist(err) {
+		t.Errorf("expected chart directory %s to be removed", removed.ChartPath())
+	}
+
+	entries := ListCacheEntries(dir)
+	if len(entries) != 1 || entries[0].Version != "2.0.0" {
+		t.Errorf("expected only version 2.0.0 to remain, got %+v", entries)
+	}
+
+	if _, err := RemoveCacheEntry(dir, "oci://example.com/charts/app", "1.0.0"); err == nil {
+		t.Error("expected error when removing a missing entry")
+	}
+}
+
+func TestRemoveCacheEntryOutsideCacheDir(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	outside := t.TempDir()
+
+	cacheFile := CacheFile{Entries: []CacheEntry{{
+		ChartURL:    "oci://example.com/charts/app",
+		Version:     "1.0.0",
+		ChartName:   "app-1.0.0",
+		DownloadDir: outside,
+	}}}
+	if err := saveCacheFile(dir, cacheFile); err != nil {
+		t.Fatalf("failed to save cache file: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(outside, "app-1.0.0"), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+
+	if _, err := RemoveCacheEntry(dir, "oci://example.com/charts/app", "1.0.0"); err == nil {
+		t.Error("expected error when entry points outside of the cache directory")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "app-1.0.0")); err != nil {
+		t.Errorf("directory outside of the cache must not be removed: %v", err)
+	}
+}
+
+func TestPruneCache(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		opts       PruneOptions
+		wantPruned []string
+	}{
+		{
+			name:       "older than",
+			opts:       PruneOptions{OlderThan: 30 * 24 * time.Hour},
+			wantPruned: []string{"old"},
+		},
+		{
+			name:       "max size evicts least recently used first",
+			opts:       PruneOptions{MaxSize: 1500},
+			wantPruned: []string{"old", "middle"},
+		},
+		{
+			name:       "nothing to prune",
+			opts:       PruneOptions{OlderThan: 365 * 24 * time.Hour, MaxSize: 1 << 20},
+			wantPruned: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			writeCachedChart(t, dir, "oci://example.com/charts/old", "1.0.0", now.Add(-60*24*time.Hour), 1000)
+			writeCachedChart(t, dir, "oci://example.com/charts/middle", "1.0.0", now.Add(-10*24*time.Hour), 1000)
+			writeCachedChart(t, dir, "oci://example.com/charts/new", "1.0.0", now, 1000)
+
+			pruned, err := PruneCache(dir, tt.opts, now)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, entry := range pruned {
+				got = append(got, filepath.Base(entry.ChartURL))
+				if _, err := os.Stat(entry.ChartPath()); !os.IsNotExist(err) {
+					t.Errorf("expected %s to be removed", entry.ChartPath())
+				}
+			}
+			if len(got) != len(tt.wantPruned) {
+				t.Fatalf("expected pruned %v, got %v", tt.wantPruned, got)
+			}
+			for i := range got {
+				if got[i] != tt.wantPruned[i] {
+					t.Errorf("expected pruned %v, got %v", tt.wantPruned, got)
+				}
+			}
+
+			if remaining := len(ListCacheEntries(dir)); remaining != 3-len(tt.wantPruned) {
+				t.Errorf("expected %d remaining entries, got %d", 3-len(tt.wantPruned), remaining)
+			}
+		})
+	}
+}
+
+func TestVerifyCache(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	valid := writeCachedChart(t, dir, "oci://example.com/charts/valid", "1.0.0", time.Now(), 10)
+	missing := writeCachedChart(t, dir, "oci://example.com/charts/missing", "1.0.0", time.Now(), 10)
+	mismatch := writeCachedChart(t, dir, "oci://example.com/charts/mismatch", "1.0.0", time.Now(), 10)
+
+	if err := os.RemoveAll(missing.ChartPath()); err != nil {
+		t.Fatalf("failed to remove chart dir: %v", err)
+	}
+	chartYaml := "apiVersion: v2\nname: mismatch\nversion: 9.9.9\n"
+	if err := os.WriteFile(filepath.Join(mismatch.ChartPath(), "Chart.yaml"), []byte(chartYaml), 0644); err != nil {
+		t.Fatalf("failed to write Chart.yaml: %v", err)
+	}
+
+	dropped, err := VerifyCache(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(dropped) != 2 {
+		t.Fatalf("expected 2 dropped entries, got %+v", dropped)
+	}
+
+	entries := ListCacheEntries(dir)
+	if len(entries) != 1 || entries[0].ChartURL != valid.ChartURL {
+		t.Errorf("expected only the valid entry to remain, got %+v", entries)
+	}
+
+	if dropped, _ := VerifyCache(dir); len(dropped) != 0 {
+		t.Errorf("expected a second verify to be clean, got %+v", dropped)
+	}
+}
+
+func TestCheckCacheEntryUpdatesLastUsed(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	lastUsed := time.Now().Add(-time.Hour)
+	writeCachedChart(t, dir, "oci://example.com/charts/app", "1.0.0", lastUsed, 10)
+
+	if _, ok := checkCacheEntry(dir, "oci://example.com/charts/app", "1.0.0", ""); !ok {
+		t.Fatal("expected cache hit")
+	}
+
+	entries := ListCacheEntries(dir)
+	if !entries[0].LastUsed.After(lastUsed) {
+		t.Errorf("expected last used time to be updated, got %v", entries[0].LastUsed)
+	}
+}
+
+func TestCheckCacheEntryWhileLocked(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	lastUsed := time.Now().Add(-time.Hour)
+	writeCachedChart(t, dir, "oci://example.com/charts/app", "1.0.0", lastUsed, 10)
+
+	// Another process holding the lock must neither block the lookup nor make it fail
+	lock, err := lockCache(dir)
+	if err != nil {
+		t.Fatalf("failed to lock cache: %v", err)
+	}
+	defer lock.Unlock()
+
+	found := make(chan bool, 1)
+	go func() {
+		_, ok := checkCacheEntry(dir, "oci://example.com/charts/app", "1.0.0", "")
+		found <- ok
+	}()
+	select {
+	case ok := <-found:
+		if !ok {
+			t.Fatal("expected cache hit")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cache lookup waited for the cache lock")
+	}
+
+	entries := ListCacheEntries(dir)
+	if !entries[0].LastUsed.Equal(lastUsed) {
+		t.Errorf("expected the last used time to be left unchanged, got %v", entries[0].LastUsed)
+	}
+}
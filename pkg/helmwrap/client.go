@@ -1,174 +1,396 @@
 package helmwrap
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/release"
+	"oras.land/oras-go/v2/registry/remote/auth"
 )
 
+// Client downloads, caches and renders Helm charts. Downloads and renders stop when their context is canceled.
 type Client interface {
-	DownloadChart(chartUrl, chartVersion string) (string, string, error)
+	DownloadChart(ctx context.Context, chartUrl, chartVersion string) (string, string, error)
 	ReadValuesFromChart(chartDir, chartName string) (string, error)
-	RenderTemplate(chartDir, chartName, valuesFile string) (map[string]interface{}, error)
-	RenderTemplateWithModifiedValue(chartDir, chartName, valuePath, valuesFile string) (map[string]interface{}, error)
+	RenderTemplate(ctx context.Context, chartDir, chartName, valuesFile string) (map[string]interface{}, error)
+	RenderTemplateWithModifiedValue(ctx context.Context, chartDir, chartName, valuePath, valuesFile string) (map[string]interface{}, *AppliedMutation, error)
+	ReleaseValues() map[string]interface{}
 }
 
 type helmClient struct {
 	settings     *cli.EnvSettings
 	actionConfig *action.Configuration
+	cacheDir     string
+	refresh      bool
+	offline      bool
+
+	logger *slog.Logger
+
+	registryOptions    RegistryOptions
+	registryTransport  http.RoundTripper
+	registryCredential auth.CredentialFunc
+
+	validateAgainstCluster bool
+	namespace              string
+	deterministic          bool
+
+	releaseName string
+	release     *release.Release
 }
 
-func NewClient() (Client, error) {
-	settings := cli.New()
-	actionConfig := new(action.Configuration)
+// ClientOption configures optional behavior of a Client created by NewClient
+type ClientOption func(*helmClient)
 
-	if err := actionConfig.Init(settings.RESTClientGetter(), settings.Namespace(), os.Getenv("HELM_DRIVER"), func(format string, v ...any) {
-		fmt.Fprintf(os.Stderr, format+"\n", v...)
-	}); err != nil {
-		return nil, fmt.Errorf("failed to initialize action config: %v", err)
+// WithCacheDir overrides the directory charts are downloaded and cached in (default: ~/.helmhound)
+func WithCacheDir(dir string) ClientOption {
+	return func(c *helmClient) {
+		c.cacheDir = dir
 	}
+}
+
+// WithRefresh makes DownloadChart re-resolve chart versions instead of trusting the cache,
+// replacing cached charts whose content digest changed. Digest-pinned charts are always served from the cache.
+func WithRefresh(refresh bool) ClientOption {
+	return func(c *helmClient) {
+		c.refresh = refresh
+	}
+}
 
-	registryClient, err := registry.NewClient()
+// WithOffline restricts DownloadChart to charts that are already cached.
+// An offline client never creates a registry client.
+func WithOffline(offline bool) ClientOption {
+	return func(c *helmClient) {
+		c.offline = offline
+	}
+}
+
+// WithClusterValidation renders charts against the cluster of the current kubeconfig,
+// using its capabilities and validating the manifests with its API server.
+// By default charts are rendered client-only and the kubeconfig is never read.
+func WithClusterValidation(validate bool) ClientOption {
+	return func(c *helmClient) {
+		c.validateAgainstCluster = validate
+	}
+}
+
+// WithDeterministicRender stubs random and time-based template functions such as randAlphaNum, uuidv4, genCA and now,
+// so that repeated renders of the same values produce the same manifests
+func WithDeterministicRender(deterministic bool) ClientOption {
+	return func(c *helmClient) {
+		c.deterministic = deterministic
+	}
+}
+
+// WithRelease analyzes changes against the deployed release with the given name in the current namespace.
+// The release's values are merged over the chart defaults, its manifest is the baseline returned by RenderTemplate,
+// and modified charts are rendered with its release name and namespace so that resources line up.
+// Renders fail if a values file is given or the chart version differs from the deployed one.
+func WithRelease(name string) ClientOption {
+	return func(c *helmClient) {
+		c.releaseName = name
+	}
+}
+
+// WithLogger sets the logger for warnings and helm's debug output (default: slog.Default())
+func WithLogger(logger *slog.Logger) ClientOption {
+	return func(c *helmClient) {
+		c.logger = logger
+	}
+}
+
+func NewClient(opts ...ClientOption) (Client, error) {
+	client := &helmClient{
+		settings:     cli.New(),
+		actionConfig: new(action.Configuration),
+		logger:       slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	if client.offline && client.refresh {
+		return nil, fmt.Errorf("refreshing charts is not possible in offline mode")
+	}
+	if client.offline && client.validateAgainstCluster {
+		return nil, fmt.Errorf("validating against the cluster is not possible in offline mode")
+	}
+	if client.offline && client.releaseName != "" {
+		return nil, fmt.Errorf("comparing against a release is not possible in offline mode")
+	}
+
+	if client.cacheDir == "" {
+		cacheDir, err := DefaultCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		client.cacheDir = cacheDir
+	}
+
+	logf := func(format string, v ...any) {
+		client.logger.Debug(fmt.Sprintf(format, v...))
+	}
+
+	if client.validateAgainstCluster || client.releaseName != "" {
+		client.namespace = client.settings.Namespace()
+		if err := client.actionConfig.Init(client.settings.RESTClientGetter(), client.namespace, os.Getenv("HELM_DRIVER"), logf); err != nil {
+			return nil, fmt.Errorf("failed to initialize action config: %v", err)
+		}
+	} else {
+		// Client-only rendering needs nothing but a logger from the action configuration;
+		// the kube client, release storage and capabilities are replaced by the install action.
+		// settings.Namespace() would load the kubeconfig, so only HELM_NAMESPACE is honored.
+		client.namespace = os.Getenv("HELM_NAMESPACE")
+		if client.namespace == "" {
+			client.namespace = "default"
+		}
+		client.actionConfig.Log = logf
+	}
+
+	if client.releaseName != "" {
+		if err := client.loadRelease(client.releaseName); err != nil {
+			return nil, err
+		}
+	}
+
+	if client.offline {
+		return client, nil
+	}
+
+	// Registry clients are created per download so that their requests carry the download's context
+	transport, err := newRegistryTransport(client.registryOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registry client: %v", err)
+	}
+	credential, err := registryCredential(client.registryOptions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create registry client: %v", err)
 	}
-	actionConfig.RegistryClient = registryClient
+	client.registryTransport = transport
+	client.registryCredential = credential
+
+	return client, nil
+}
+
+// loadRelease fetches the latest revision of the named release from the release storage of the action configuration
+func (c *helmClient) loadRelease(name string) error {
+	rel, err := action.NewGet(c.actionConfig).Run(name)
+	if err != nil {
+		return fmt.Errorf("failed to get release %s: %v", name, err)
+	}
+	c.release = rel
+	return nil
+}
 
-	return &helmClient{
-		settings:     settings,
-		actionConfig: actionConfig,
-	}, nil
+// ReleaseValues returns a copy of the values the baseline release was deployed with, or nil if the client
+// does not compare against a release
+func (c *helmClient) ReleaseValues() map[string]interface{} {
+	if c.release == nil || len(c.release.Config) == 0 {
+		return nil
+	}
+	values := make(map[string]interface{})
+	copyMap(c.release.Config, values)
+	return values
 }
 
-func (c *helmClient) DownloadChart(chartUrl, chartVersion string) (string, string, error) {
-	homeDir, err := os.UserHomeDir()
+// DownloadChart downloads the chart into the cache and returns the cache directory and the chart directory name.
+// chartVersion may pin the chart content with a digest, e.g. "@sha256:..." or "1.2.3@sha256:...".
+// For OCI charts the digest is the manifest digest; otherwise it is the digest of the chart archive.
+// DownloadChart returns ctx.Err() as soon as ctx is canceled or its deadline passes.
+func (c *helmClient) DownloadChart(ctx context.Context, chartUrl, chartVersion string) (string, string, error) {
+	helmhoundDir := c.cacheDir
+
+	version, digest, err := parseChartVersion(chartVersion)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to get user home directory: %v", err)
+		return "", "", err
 	}
-	helmhoundDir := filepath.Join(homeDir, ".helmhound")
 
 	if err := os.MkdirAll(helmhoundDir, 0755); err != nil {
 		return "", "", fmt.Errorf("failed to create helmhound directory: %v", err)
 	}
 
-	// Check cache first
-	if entry, exists := checkCacheEntry(helmhoundDir, chartUrl, chartVersion); exists {
-		return entry.DownloadDir, entry.ChartName, nil
+	// Check cache first; pinned content never changes, so it is served from the cache even when refreshing
+	if !c.refresh || digest != "" {
+		if entry, exists := checkCacheEntry(helmhoundDir, chartUrl, version, digest); exists {
+			return entry.DownloadDir, entry.ChartName, nil
+		}
 	}
 
-	// Extract chart name from URL for directory naming
-	baseName := extractChartNameFromURL(chartUrl)
+	if c.offline {
+		return "", "", fmt.Errorf("chart %s version %s is not cached and cannot be downloaded in offline mode", chartUrl, chartVersion)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return "", "", err
+	}
 
-	// The chart will be downloaded using the actual chart name from the repository
-	actualChartDir := filepath.Join(helmhoundDir, baseName)
+	registryClient, authorizer, err := c.newRegistryClient(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create registry client: %v", err)
+	}
+
+	// Download into a private temporary directory so that concurrent runs never
+	// observe a partially extracted chart in the shared cache directory
+	tmpDir, err := os.MkdirTemp(helmhoundDir, ".download-")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create download directory: %v", err)
+	}
+	cleanup := func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			c.logger.Warn("failed to clean up download directory", "error", err)
+		}
+	}
+	abandoned := false
+	defer func() {
+		if !abandoned {
+			cleanup()
+		}
+	}()
 
 	// Create Pull action with proper configuration using NewPullWithOpts and WithConfig
 	pull := action.NewPullWithOpts(action.WithConfig(c.actionConfig))
-	pull.Version = chartVersion
-	pull.Untar = true
+	pull.SetRegistryClient(registryClient)
+	pull.Version = version
 	pull.Settings = c.settings
-	pull.DestDir = helmhoundDir
-
-	// Check if we already have the chart, if so read its version
-	if _, err := os.Stat(actualChartDir); err == nil {
-		// Chart directory already exists, check if it's the right version
-		existingVersion, err := readChartVersion(actualChartDir)
-		if err == nil {
-			finalChartName := fmt.Sprintf("%s-%s", baseName, existingVersion)
-			finalChartDir := filepath.Join(helmhoundDir, finalChartName)
-
-			// Check if versioned directory exists
-			if _, err := os.Stat(finalChartDir); err == nil {
-				// Add to cache and return
-				addCacheEntry(helmhoundDir, chartUrl, existingVersion, finalChartName, helmhoundDir)
-				return helmhoundDir, finalChartName, nil
+	pull.DestDir = tmpDir
+	pull.Username = c.registryOptions.Username
+	pull.Password = c.registryOptions.Password
+	pull.CaFile = c.registryOptions.CAFile
+	pull.InsecureSkipTLSverify = c.registryOptions.InsecureSkipTLSVerify
+	pull.PlainHTTP = c.registryOptions.PlainHTTP
+
+	chartRef := chartUrl
+	isOCI := registry.IsOCI(chartUrl)
+	if isOCI && digest != "" {
+		// Helm resolves "oci://registry/chart@sha256:..." to the pinned manifest
+		chartRef = chartUrl + "@" + digest
+	}
+
+	// Download the chart archive. Helm's chart repository getter takes no context, so when ctx ends first
+	// the pull is abandoned and removes its download directory once it returns.
+	pulled := make(chan error, 1)
+	go func() {
+		_, err := pull.Run(chartRef)
+		pulled <- err
+	}()
+	select {
+	case <-ctx.Done():
+		abandoned = true
+		go func() {
+			<-pulled
+			cleanup()
+		}()
+		return "", "", ctx.Err()
+	case err := <-pulled:
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", "", ctx.Err()
 			}
-
-			// Rename existing chart to versioned directory
-			if err := os.Rename(actualChartDir, finalChartDir); err == nil {
-				// Add to cache and return
-				addCacheEntry(helmhoundDir, chartUrl, existingVersion, finalChartName, helmhoundDir)
-				return helmhoundDir, finalChartName, nil
-			}
-		}
-
-		// If there's an issue with existing chart, remove it and redownload
-		if err := os.RemoveAll(actualChartDir); err != nil {
-			return "", "", fmt.Errorf("failed to remove existing chart directory: %v", err)
+			return "", "", fmt.Errorf("failed to pull chart: %v", err)
 		}
 	}
 
-	// Download the chart
-	_, err = pull.Run(chartUrl)
+	downloaded, err := extractDownloadedChart(tmpDir)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to pull chart: %v", err)
+		return "", "", err
 	}
 
-	// Read the actual version from Chart.yaml
-	actualVersion, err := readChartVersion(actualChartDir)
-	if err != nil {
-		// Clean up on error
-		if removeErr := os.RemoveAll(actualChartDir); removeErr != nil {
-			// Log the cleanup error but return the original error
-			fmt.Fprintf(os.Stderr, "failed to clean up chart directory: %v\n", removeErr)
-		}
-		return "", "", fmt.Errorf("failed to read chart version: %v", err)
+	entry := CacheEntry{
+		ChartURL:    chartUrl,
+		Version:     downloaded.metadata.Version,
+		ChartName:   fmt.Sprintf("%s-%s-%s", downloaded.metadata.Name, downloaded.metadata.Version, shortDigest(downloaded.digest)),
+		DownloadDir: helmhoundDir,
+		Digest:      downloaded.digest,
 	}
 
-	// Create final chart name with actual version
-	finalChartName := fmt.Sprintf("%s-%s", baseName, actualVersion)
-	finalChartDir := filepath.Join(helmhoundDir, finalChartName)
-
-	// Check if the versioned chart already exists
-	if _, err := os.Stat(finalChartDir); err == nil {
-		// Versioned chart already exists, remove the downloaded one and return existing
-		if err := os.RemoveAll(actualChartDir); err != nil {
-			return "", "", fmt.Errorf("failed to remove duplicate chart directory: %v", err)
+	if isOCI {
+		if digest != "" {
+			entry.ManifestDigest = digest
+		} else {
+			entry.ManifestDigest = c.resolveManifestDigest(ctx, authorizer, chartUrl, entry.Version)
 		}
-		// Add to cache and return
-		addCacheEntry(helmhoundDir, chartUrl, actualVersion, finalChartName, helmhoundDir)
-		return helmhoundDir, finalChartName, nil
+	} else if digest != "" && digest != downloaded.digest {
+		return "", "", fmt.Errorf("digest mismatch for chart %s: expected %s, got %s", chartUrl, digest, downloaded.digest)
+	}
+
+	// Publish the chart directory and its cache entry while holding the cache lock.
+	// Directories are named after their content, so if another process already
+	// published the same chart, keep theirs.
+	// Replaced charts are only removed once cache.yaml no longer refers to them.
+	finalChartDir := entry.ChartPath()
+	lock, err := lockCache(helmhoundDir)
+	if err != nil {
+		return "", "", err
 	}
+	defer lock.Unlock()
 
-	// Rename downloaded directory to final versioned directory
-	if err := os.Rename(actualChartDir, finalChartDir); err != nil {
-		if removeErr := os.RemoveAll(actualChartDir); removeErr != nil {
-			// Log the cleanup error but return the original error
-			fmt.Fprintf(os.Stderr, "failed to clean up chart directory: %v\n", removeErr)
+	cacheFile := loadCacheFile(helmhoundDir)
+	if _, err := os.Stat(finalChartDir); os.IsNotExist(err) {
+		if err := os.Rename(downloaded.dir, finalChartDir); err != nil {
+			return "", "", fmt.Errorf("failed to move chart into cache: %v", err)
 		}
-		return "", "", fmt.Errorf("failed to rename chart directory: %v", err)
 	}
+	stale := addCacheEntry(&cacheFile, entry)
+	if err := saveCacheFile(helmhoundDir, cacheFile); err != nil {
+		return "", "", err
+	}
+	removeStaleCharts(c.logger, helmhoundDir, cacheFile, stale)
 
-	// Add to cache
-	addCacheEntry(helmhoundDir, chartUrl, actualVersion, finalChartName, helmhoundDir)
+	return helmhoundDir, entry.ChartName, nil
+}
 
-	return helmhoundDir, finalChartName, nil
+// downloadedChart is a chart archive that has been pulled and extracted into a temporary directory
+type downloadedChart struct {
+	dir      string
+	metadata ChartMetadata
+	digest   string
 }
 
-// extractChartNameFromURL extracts chart name from various chart URL formats
-func extractChartNameFromURL(chartUrl string) string {
-	// Handle OCI URLs like "oci://ghcr.io/prometheus-community/charts/kube-prometheus-stack"
-	if strings.HasPrefix(chartUrl, "oci://") {
-		parts := strings.Split(chartUrl, "/")
-		if len(parts) > 0 {
-			return parts[len(parts)-1] // Return the last part as chart name
-		}
+// extractDownloadedChart expands the chart archive pulled into downloadDir and returns the chart directory,
+// its metadata and the digest of the archive
+func extractDownloadedChart(downloadDir string) (downloadedChart, error) {
+	archives, err := filepath.Glob(filepath.Join(downloadDir, "*.tgz"))
+	if err != nil || len(archives) != 1 {
+		return downloadedChart{}, fmt.Errorf("expected exactly one chart archive in %s, found %d", downloadDir, len(archives))
+	}
+
+	digest, err := fileDigest(archives[0])
+	if err != nil {
+		return downloadedChart{}, fmt.Errorf("failed to compute chart archive digest: %v", err)
+	}
+
+	extractDir := filepath.Join(downloadDir, "extracted")
+	if err := chartutil.ExpandFile(extractDir, archives[0]); err != nil {
+		return downloadedChart{}, fmt.Errorf("failed to extract chart archive: %v", err)
+	}
+
+	// A chart archive always contains a single top-level directory named after the chart
+	entries, err := os.ReadDir(extractDir)
+	if err != nil {
+		return downloadedChart{}, fmt.Errorf("failed to read extracted chart: %v", err)
 	}
+	if len(entries) != 1 || !entries[0].IsDir() {
+		return downloadedChart{}, fmt.Errorf("unexpected layout of chart archive %s", filepath.Base(archives[0]))
+	}
+	chartDir := filepath.Join(extractDir, entries[0].Name())
 
-	// Handle other URL formats or fallback
-	parts := strings.Split(strings.TrimSuffix(chartUrl, "/"), "/")
-	if len(parts) > 0 {
-		return parts[len(parts)-1]
+	metadata, err := readChartMetadata(chartDir)
+	if err != nil {
+		return downloadedChart{}, fmt.Errorf("failed to read chart version: %v", err)
 	}
 
-	return "chart" // fallback name
+	return downloadedChart{dir: chartDir, metadata: metadata, digest: digest}, nil
 }
 
 // ChartMetadata represents the structure of Chart.yaml
@@ -177,45 +399,44 @@ type ChartMetadata struct {
 	Version string `yaml:"version"`
 }
 
-// CacheEntry represents a single cache entry
-type CacheEntry struct {
-	ChartURL    string `yaml:"chart_url"`
-	Version     string `yaml:"version"`
-	ChartName   string `yaml:"chart_name"`
-	DownloadDir string `yaml:"download_dir"`
-}
-
-// CacheFile represents the structure of cache.yaml
-type CacheFile struct {
-	Entries []CacheEntry `yaml:"entries"`
-}
-
 // readChartVersion reads the version from Chart.yaml in the downloaded chart directory
 func readChartVersion(chartDir string) (string, error) {
+	metadata, err := readChartMetadata(chartDir)
+	if err != nil {
+		return "", err
+	}
+	return metadata.Version, nil
+}
+
+// readChartMetadata reads the name and version from Chart.yaml in the chart directory
+func readChartMetadata(chartDir string) (ChartMetadata, error) {
 	chartYamlPath := filepath.Join(chartDir, "Chart.yaml")
 
 	// Check if Chart.yaml exists
 	if _, err := os.Stat(chartYamlPath); os.IsNotExist(err) {
-		return "", fmt.Errorf("chart.yaml not found in directory: %s", chartDir)
+		return ChartMetadata{}, fmt.Errorf("chart.yaml not found in directory: %s", chartDir)
 	}
 
 	// Read Chart.yaml content
 	content, err := os.ReadFile(chartYamlPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to read Chart.yaml: %v", err)
+		return ChartMetadata{}, fmt.Errorf("failed to read Chart.yaml: %v", err)
 	}
 
-	// Parse YAML to extract version
+	// Parse YAML to extract name and version
 	var metadata ChartMetadata
 	if err := yaml.Unmarshal(content, &metadata); err != nil {
-		return "", fmt.Errorf("failed to parse Chart.yaml: %v", err)
+		return ChartMetadata{}, fmt.Errorf("failed to parse Chart.yaml: %v", err)
 	}
 
 	if metadata.Version == "" {
-		return "", fmt.Errorf("version not found in Chart.yaml")
+		return ChartMetadata{}, fmt.Errorf("version not found in Chart.yaml")
+	}
+	if metadata.Name == "" {
+		return ChartMetadata{}, fmt.Errorf("name not found in Chart.yaml")
 	}
 
-	return metadata.Version, nil
+	return metadata, nil
 }
 
 // ReadValuesFromChart reads values.yaml from downloaded chart directory
@@ -250,6 +471,16 @@ func (c *helmClient) mergeValues(chartDir, chartName, valuesFile string) (map[st
 		return nil, fmt.Errorf("failed to parse default values: %v", err)
 	}
 
+	// The values the release was deployed with override the chart defaults
+	if c.release != nil && len(c.release.Config) > 0 {
+		releaseValues := make(map[string]interface{})
+		copyMap(defaultValues, releaseValues)
+		releaseConfig := make(map[string]interface{})
+		copyMap(c.release.Config, releaseConfig)
+		deepMergeMap(releaseValues, releaseConfig)
+		defaultValues = releaseValues
+	}
+
 	// If no custom values file provided, return default values
 	if valuesFile == "" {
 		return defaultValues, nil
@@ -291,138 +522,186 @@ func deepMergeMap(dst, src map[string]interface{}) {
 	}
 }
 
-// RenderTemplate renders the Helm chart with merged values and returns the result as map[string]interface{}
-func (c *helmClient) RenderTemplate(chartDir, chartName, valuesFile string) (map[string]interface{}, error) {
+// RenderTemplate renders the Helm chart with merged values and returns the result as map[string]interface{}.
+// When analyzing a release, the deployed manifest of the release is returned instead.
+func (c *helmClient) RenderTemplate(ctx context.Context, chartDir, chartName, valuesFile string) (map[string]interface{}, error) {
+	if c.release != nil {
+		if err := c.checkRelease(chartDir, chartName, valuesFile); err != nil {
+			return nil, err
+		}
+		return parseManifest(c.release.Manifest), nil
+	}
+
 	// Get merged values
 	mergedValues, err := c.mergeValues(chartDir, chartName, valuesFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to merge values: %v", err)
 	}
 
-	// Load chart from the downloaded directory
-	chartPath := filepath.Join(chartDir, chartName)
-	chart, err := loader.Load(chartPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load chart: %v", err)
-	}
-
-	// Create install action to render templates
-	install := action.NewInstall(c.actionConfig)
-	install.DryRun = true // This makes it only render templates without installing
-	install.ReleaseName = "helmhound-render"
-	install.Namespace = c.settings.Namespace()
-	install.IsUpgrade = false
-	install.ClientOnly = true
-	install.IncludeCRDs = true
-	install.SkipSchemaValidation = true
-
-	// Remove kubeVersion constraint from chart metadata to avoid compatibility issues
-	originalKubeVersion := chart.Metadata.KubeVersion
-	chart.Metadata.KubeVersion = ""
-
-	// Run the install action in dry-run mode to get rendered templates
-	release, err := install.Run(chart, mergedValues) // Use merged values
+	return c.renderChart(ctx, chartDir, chartName, mergedValues)
+}
 
-	// Restore original kubeVersion constraint
-	chart.Metadata.KubeVersion = originalKubeVersion
+// checkRelease ensures that the modified renders differ from the deployed release only by the mutation:
+// they must start from the release values alone and render the chart version the release was deployed with
+func (c *helmClient) checkRelease(chartDir, chartName, valuesFile string) error {
+	if valuesFile != "" {
+		return fmt.Errorf("a values file cannot be combined with release %s, whose deployed values are the baseline", c.release.Name)
+	}
+	if c.release.Chart == nil || c.release.Chart.Metadata == nil {
+		return nil
+	}
 
+	metadata, err := readChartMetadata(filepath.Join(chartDir, chartName))
 	if err != nil {
-		return nil, fmt.Errorf("failed to render templates: %v", err)
+		return err
 	}
+	if deployed := c.release.Chart.Metadata.Version; metadata.Version != deployed {
+		return fmt.Errorf("release %s runs chart version %s, but version %s was downloaded; analyze the deployed version instead", c.release.Name, deployed, metadata.Version)
+	}
+	return nil
+}
 
-	// Parse the manifest as multiple YAML documents separated by ---
-	documents := strings.Split(release.Manifest, "---\n")
-	manifestMap := make(map[string]interface{})
-
-	for i, doc := range documents {
-		doc = strings.TrimSpace(doc)
-		if doc == "" {
-			continue
-		}
-
-		var docData map[string]interface{}
-		if err := yaml.Unmarshal([]byte(doc), &docData); err != nil {
-			// If parsing as map fails, store as raw string
-			manifestMap[fmt.Sprintf("document_%d", i)] = doc
-			continue
-		}
-
-		// Use kind and name as key if available
-		if kind, ok := docData["kind"].(string); ok {
-			key := kind
-			if metadata, ok := docData["metadata"].(map[string]interface{}); ok {
-				if name, ok := metadata["name"].(string); ok {
-					key = fmt.Sprintf("%s_%s", kind, name)
-				}
-			}
-			manifestMap[key] = docData
-		} else {
-			manifestMap[fmt.Sprintf("document_%d", i)] = docData
+// RenderTemplateWithModifiedValue renders the Helm chart with a modified value at the specified path.
+// The applied mutation is returned whenever the value was modified, even if rendering fails.
+func (c *helmClient) RenderTemplateWithModifiedValue(ctx context.Context, chartDir, chartName, valuePath, valuesFile string) (map[string]interface{}, *AppliedMutation, error) {
+	if c.release != nil {
+		if err := c.checkRelease(chartDir, chartName, valuesFile); err != nil {
+			return nil, nil, err
 		}
 	}
 
-	return manifestMap, nil
-}
-
-// RenderTemplateWithModifiedValue renders the Helm chart with a modified value at the specified path
-func (c *helmClient) RenderTemplateWithModifiedValue(chartDir, chartName, valuePath, valuesFile string) (map[string]interface{}, error) {
 	// Get merged values
 	mergedValues, err := c.mergeValues(chartDir, chartName, valuesFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to merge values: %v", err)
+		return nil, nil, fmt.Errorf("failed to merge values: %v", err)
 	}
 
 	// Convert merged values to YAML for GetValueType function
 	mergedValuesYAML, err := yaml.Marshal(mergedValues)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal merged values: %v", err)
+		return nil, nil, fmt.Errorf("failed to marshal merged values: %v", err)
 	}
 
 	// Get the current value type from merged values
 	valueType, err := GetValueType(string(mergedValuesYAML), valuePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to determine value type at path %s: %v", valuePath, err)
+		return nil, nil, fmt.Errorf("failed to determine value type at path %s: %v", valuePath, err)
 	}
 
 	// Modify the value based on its type
 	modifiedValues, err := modifyValueAtPath(mergedValues, valuePath, valueType)
 	if err != nil {
-		return nil, fmt.Errorf("failed to modify value at path %s: %v", valuePath, err)
+		return nil, nil, fmt.Errorf("failed to modify value at path %s: %v", valuePath, err)
+	}
+
+	// The chart is loaded once for describing the mutation and rendering it
+	loaded, err := c.loadChart(chartDir, chartName)
+	if err != nil {
+		return nil, nil, err
+	}
+	mutation, err := newAppliedMutation(ctx, loaded, valuePath, valueType, mergedValues, modifiedValues)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Load chart from the downloaded directory
-	chartPath := filepath.Join(chartDir, chartName)
-	chart, err := loader.Load(chartPath)
+	manifests, err := c.renderLoadedChart(ctx, loaded, modifiedValues)
+	if err != nil {
+		return nil, mutation, err
+	}
+	return manifests, mutation, nil
+}
+
+// renderChart renders the cached chart with the given values and returns the manifests keyed by kind and name
+func (c *helmClient) renderChart(ctx context.Context, chartDir, chartName string, values map[string]interface{}) (map[string]interface{}, error) {
+	loaded, err := c.loadChart(chartDir, chartName)
+	if err != nil {
+		return nil, err
+	}
+	return c.renderLoadedChart(ctx, loaded, values)
+}
+
+// loadChart loads the cached chart, replacing its nondeterministic template functions if requested
+func (c *helmClient) loadChart(chartDir, chartName string) (*chart.Chart, error) {
+	loaded, err := loader.Load(filepath.Join(chartDir, chartName))
 	if err != nil {
 		return nil, fmt.Errorf("failed to load chart: %v", err)
 	}
+	if c.deterministic {
+		if err := stubNondeterministicFuncs(loaded); err != nil {
+			return nil, err
+		}
+	}
+	return loaded, nil
+}
+
+// renderLoadedChart renders a loaded chart with the given values and returns the manifests keyed by kind and name
+func (c *helmClient) renderLoadedChart(ctx context.Context, loaded *chart.Chart, values map[string]interface{}) (map[string]interface{}, error) {
+
+	// Client-only installs replace the kube client, release storage and capabilities of their configuration,
+	// so each of them gets its own instead of sharing one with renders abandoned after a timeout
+	cfg := c.actionConfig
+	if !c.validateAgainstCluster {
+		cfg = &action.Configuration{Log: c.actionConfig.Log}
+	}
 
 	// Create install action to render templates
-	install := action.NewInstall(c.actionConfig)
+	install := action.NewInstall(cfg)
 	install.DryRun = true // This makes it only render templates without installing
 	install.ReleaseName = "helmhound-render"
-	install.Namespace = c.settings.Namespace()
+	install.Namespace = c.namespace
 	install.IsUpgrade = false
-	install.ClientOnly = true
+	install.ClientOnly = !c.validateAgainstCluster
 	install.IncludeCRDs = true
 	install.SkipSchemaValidation = true
+	if c.release != nil {
+		// Deployed manifests never contain the chart's crds/ directory
+		install.ReleaseName = c.release.Name
+		install.Namespace = c.release.Namespace
+		install.IncludeCRDs = false
+	}
+	if c.validateAgainstCluster {
+		// Use the cluster's capabilities and validate the manifests against its API server.
+		// Nothing is applied in a dry run, so resources owned by other releases must not fail the render.
+		install.DryRunOption = "server"
+		install.TakeOwnership = true
+	}
 
 	// Remove kubeVersion constraint from chart metadata to avoid compatibility issues
-	originalKubeVersion := chart.Metadata.KubeVersion
-	chart.Metadata.KubeVersion = ""
-
-	// Run the install action with modified values
-	release, err := install.Run(chart, modifiedValues)
-
-	// Restore original kubeVersion constraint
-	chart.Metadata.KubeVersion = originalKubeVersion
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to render templates: %v", err)
+	loaded.Metadata.KubeVersion = ""
+
+	// Run the install action in dry-run mode to get rendered templates.
+	// Template execution does not observe ctx, so a render that outlives ctx is abandoned and finishes in the background.
+	type renderResult struct {
+		release *release.Release
+		err     error
+	}
+	rendered := make(chan renderResult, 1)
+	go func() {
+		rel, err := install.RunWithContext(ctx, loaded, values)
+		rendered <- renderResult{release: rel, err: err}
+	}()
+
+	var result renderResult
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result = <-rendered:
+	}
+	if result.err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, newRenderError(result.err)
 	}
+	release := result.release
+
+	return parseManifest(release.Manifest), nil
+}
 
+// parseManifest splits a rendered manifest into its documents keyed by "Kind_name"
+func parseManifest(manifest string) map[string]interface{} {
 	// Parse the manifest as multiple YAML documents separated by ---
-	documents := strings.Split(release.Manifest, "---\n")
+	documents := strings.Split(manifest, "---\n")
 	manifestMap := make(map[string]interface{})
 
 	for i, doc := range documents {
@@ -452,7 +731,7 @@ func (c *helmClient) RenderTemplateWithModifiedValue(chartDir, chartName, valueP
 		}
 	}
 
-	return manifestMap, nil
+	return manifestMap
 }
 
 // modifyValueAtPath modifies a value at the specified path based on its type
@@ -579,113 +858,57 @@ func copySlice(src []interface{}) []interface{} {
 	return dst
 }
 
-// setValueAtPath sets a value at the specified path in the map
+// setValueAtPath sets a value at the specified path in the map, creating missing intermediate maps.
+// "[i]" segments index into existing lists.
 func setValueAtPath(data map[string]interface{}, path string, value interface{}) error {
 	if path == "" {
 		return fmt.Errorf("empty path")
 	}
 
 	keys := splitPath(path)
-	current := data
+	var current interface{} = data
 
 	// Navigate to the parent of the target key
 	for i := 0; i < len(keys)-1; i++ {
 		key := keys[i]
-		if next, ok := current[key]; ok {
-			if nextMap, ok := next.(map[string]interface{}); ok {
-				current = nextMap
-			} else {
-				return fmt.Errorf("cannot navigate through non-map value at key '%s'", key)
+		if index, ok := pathIndex(key); ok {
+			list, ok := current.([]interface{})
+			if !ok || index >= len(list) {
+				return fmt.Errorf("cannot navigate to index %s", key)
 			}
+			current = list[index]
+			continue
+		}
+
+		currentMap, ok := current.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cannot navigate through non-map value at key '%s'", keys[i-1])
+		}
+		if next, ok := currentMap[key]; ok {
+			current = next
 		} else {
 			// Create missing intermediate maps
 			newMap := make(map[string]interface{})
-			current[key] = newMap
+			currentMap[key] = newMap
 			current = newMap
 		}
 	}
 
 	// Set the final value
 	finalKey := keys[len(keys)-1]
-	current[finalKey] = value
-
-	return nil
-}
-
-// checkCacheEntry checks if a chart with given URL and version exists in cache
-func checkCacheEntry(helmhoundDir, chartUrl, chartVersion string) (CacheEntry, bool) {
-	cacheFile := loadCacheFile(helmhoundDir)
-
-	for _, entry := range cacheFile.Entries {
-		if entry.ChartURL == chartUrl && entry.Version == chartVersion {
-			// Verify that the cached directory still exists
-			chartPath := filepath.Join(entry.DownloadDir, entry.ChartName)
-			if _, err := os.Stat(chartPath); err == nil {
-				return entry, true
-			}
-		}
-	}
-
-	return CacheEntry{}, false
-}
-
-// addCacheEntry adds a new entry to the cache file
-func addCacheEntry(helmhoundDir, chartUrl, version, chartName, downloadDir string) {
-	cacheFile := loadCacheFile(helmhoundDir)
-
-	// Check if entry already exists (avoid duplicates)
-	for _, entry := range cacheFile.Entries {
-		if entry.ChartURL == chartUrl && entry.Version == version {
-			return // Already exists
+	if index, ok := pathIndex(finalKey); ok {
+		list, ok := current.([]interface{})
+		if !ok || index >= len(list) {
+			return fmt.Errorf("cannot set index %s", finalKey)
 		}
+		list[index] = value
+		return nil
 	}
-
-	// Add new entry
-	newEntry := CacheEntry{
-		ChartURL:    chartUrl,
-		Version:     version,
-		ChartName:   chartName,
-		DownloadDir: downloadDir,
-	}
-
-	cacheFile.Entries = append(cacheFile.Entries, newEntry)
-	saveCacheFile(helmhoundDir, cacheFile)
-}
-
-// loadCacheFile loads the cache file or returns empty cache if file doesn't exist
-func loadCacheFile(helmhoundDir string) CacheFile {
-	cacheFilePath := filepath.Join(helmhoundDir, "cache.yaml")
-
-	// If cache file doesn't exist, return empty cache
-	if _, err := os.Stat(cacheFilePath); os.IsNotExist(err) {
-		return CacheFile{Entries: []CacheEntry{}}
-	}
-
-	// Read and parse cache file
-	content, err := os.ReadFile(cacheFilePath)
-	if err != nil {
-		return CacheFile{Entries: []CacheEntry{}}
-	}
-
-	var cacheFile CacheFile
-	if err := yaml.Unmarshal(content, &cacheFile); err != nil {
-		return CacheFile{Entries: []CacheEntry{}}
-	}
-
-	return cacheFile
-}
-
-// saveCacheFile saves the cache file to disk
-func saveCacheFile(helmhoundDir string, cacheFile CacheFile) {
-	cacheFilePath := filepath.Join(helmhoundDir, "cache.yaml")
-
-	content, err := yaml.Marshal(cacheFile)
-	if err != nil {
-		return // Silent failure for now
+	currentMap, ok := current.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("cannot set key '%s' on non-map value", finalKey)
 	}
+	currentMap[finalKey] = value
 
-	if err := os.WriteFile(cacheFilePath, content, 0644); err != nil {
-		// Log the error but don't fail the operation
-		fmt.Fprintf(os.Stderr, "failed to write cache file: %v\n", err)
-	}
+	return nil
 }
@@ -1,8 +1,26 @@
 package helmwrap
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
 )
 
 func TestModifyValueAtPath(t *testing.T) {
@@ -100,6 +118,29 @@ func TestModifyValueAtPath(t *testing.T) {
 			valueType: ValueTypeSlice,
 			expected:  []interface{}{"dev", "prod", "helmhound-test-element"},
 		},
+		{
+			name: "modify list element",
+			values: map[string]interface{}{
+				"service": map[string]interface{}{
+					"ports": []interface{}{
+						map[string]interface{}{"name": "http", "port": 80},
+						map[string]interface{}{"name": "https", "port": 443},
+					},
+				},
+			},
+			path:      "service.ports[1].port",
+			valueType: ValueTypeInt,
+			expected:  444,
+		},
+		{
+			name: "modify missing list element",
+			values: map[string]interface{}{
+				"tags": []interface{}{"tag1"},
+			},
+			path:        "tags[1]",
+			valueType:   ValueTypeString,
+			expectError: true,
+		},
 		{
 			name: "modify map value",
 			values: map[string]interface{}{
@@ -249,6 +290,42 @@ func TestSetValueAtPath(t *testing.T) {
 			path:  "new.nested.key",
 			value: "value",
 		},
+		{
+			name: "set list element",
+			data: map[string]interface{}{
+				"tags": []interface{}{"tag1", "tag2"},
+			},
+			path:  "tags[1]",
+			value: "new",
+		},
+		{
+			name: "set field of list element",
+			data: map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{"name": "app"},
+				},
+			},
+			path:  "containers[0].image.tag",
+			value: "v2",
+		},
+		{
+			name: "index out of range",
+			data: map[string]interface{}{
+				"tags": []interface{}{"tag1"},
+			},
+			path:        "tags[3]",
+			value:       "new",
+			expectError: true,
+		},
+		{
+			name: "index into map",
+			data: map[string]interface{}{
+				"config": map[string]interface{}{"key": "value"},
+			},
+			path:        "config[0]",
+			value:       "new",
+			expectError: true,
+		},
 		{
 			name:        "empty path",
 			data:        map[string]interface{}{},
@@ -289,3 +366,581 @@ func TestSetValueAtPath(t *testing.T) {
 		})
 	}
 }
+
+// packageTestChart writes a chart archive named <name>-<version>.tgz into dir and returns its path
+func packageTestChart(t *testing.T, dir, name, version string) string {
+	t.Helper()
+
+	return packageTestChartWithValues(t, dir, name, version, "replicaCount: 1\nimage:\n  repository: nginx\n")
+}
+
+// packageTestChartWithValues is packageTestChart with custom values.yaml content
+func packageTestChartWithValues(t *testing.T, dir, name, version, values string) string {
+	t.Helper()
+
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .Release.Name }}-{{ .Chart.Name }}
+spec:
+  replicas: {{ .Values.replicaCount }}
+  template:
+    spec:
+      containers:
+        - name: app
+          image: {{ .Values.image.repository }}
+`
+
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: name, Version: version},
+		Raw:      []*chart.File{{Name: chartutil.ValuesfileName, Data: []byte(values)}},
+		Templates: []*chart.File{
+			{Name: "templates/deployment.yaml", Data: []byte(deployment)},
+		},
+	}
+
+	archive, err := chartutil.Save(c, dir)
+	if err != nil {
+		t.Fatalf("failed to package test chart: %v", err)
+	}
+	return archive
+}
+
+func TestDownloadChartConcurrent(t *testing.T) {
+	t.Parallel()
+
+	archiveDir := t.TempDir()
+	versions := []string{"1.0.0", "1.1.0", "2.0.0"}
+	for _, version := range versions {
+		packageTestChart(t, archiveDir, "app", version)
+	}
+	server := httptest.NewServer(http.FileServer(http.Dir(archiveDir)))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+
+	const downloadsPerVersion = 4
+	var wg sync.WaitGroup
+	errs := make(chan error, len(versions)*downloadsPerVersion)
+	for _, version := range versions {
+		for i := 0; i < downloadsPerVersion; i++ {
+			wg.Add(1)
+			go func(version string) {
+				defer wg.Done()
+
+				client, err := NewClient(WithCacheDir(cacheDir))
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				chartUrl := fmt.Sprintf("%s/app-%s.tgz", server.URL, version)
+				chartDir, chartName, err := client.DownloadChart(t.Context(), chartUrl, version)
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				if _, err := client.ReadValuesFromChart(chartDir, chartName); err != nil {
+					errs <- fmt.Errorf("chart %s is incomplete: %v", chartName, err)
+				}
+			}(version)
+		}
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	entries := ListCacheEntries(cacheDir)
+	if len(entries) != len(versions) {
+		t.Fatalf("expected %d cache entries, got %d: %+v", len(versions), len(entries), entries)
+	}
+	for i, entry := range entries {
+		if entry.Version != versions[i] || !strings.HasPrefix(entry.ChartName, "app-"+versions[i]+"-") || entry.Digest == "" {
+			t.Errorf("unexpected cache entry %+v", entry)
+		}
+	}
+
+	dirEntries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatalf("failed to read cache dir: %v", err)
+	}
+	for _, entry := range dirEntries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			t.Errorf("temporary file %s left behind in cache directory", entry.Name())
+		}
+	}
+}
+
+func TestDownloadChartDigest(t *testing.T) {
+	t.Parallel()
+
+	archiveDir := t.TempDir()
+	archive := packageTestChart(t, archiveDir, "app", "1.0.0")
+	server := httptest.NewServer(http.FileServer(http.Dir(archiveDir)))
+	defer server.Close()
+
+	chartUrl := server.URL + "/app-1.0.0.tgz"
+	cacheDir := t.TempDir()
+
+	client, err := NewClient(WithCacheDir(cacheDir))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	_, originalName, err := client.DownloadChart(t.Context(), chartUrl, "1.0.0")
+	if err != nil {
+		t.Fatalf("failed to download chart: %v", err)
+	}
+	originalDigest, err := fileDigest(archive)
+	if err != nil {
+		t.Fatalf("failed to compute digest: %v", err)
+	}
+	if entries := ListCacheEntries(cacheDir); len(entries) != 1 || entries[0].Digest != originalDigest {
+		t.Fatalf("expected cache entry with digest %s, got %+v", originalDigest, entries)
+	}
+
+	// Re-publish different content under the same version, as a mutable tag would
+	if err := os.Remove(archive); err != nil {
+		t.Fatalf("failed to remove archive: %v", err)
+	}
+	republished := packageTestChartWithValues(t, archiveDir, "app", "1.0.0", "replicaCount: 3\nimage:\n  repository: nginx\n")
+	republishedDigest, err := fileDigest(republished)
+	if err != nil {
+		t.Fatalf("failed to compute digest: %v", err)
+	}
+
+	t.Run("cached without refresh", func(t *testing.T) {
+		_, name, err := client.DownloadChart(t.Context(), chartUrl, "1.0.0")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if name != originalName {
+			t.Errorf("expected cached chart %s, got %s", originalName, name)
+		}
+	})
+
+	t.Run("pinned to a mismatching digest", func(t *testing.T) {
+		wrong := "sha256:" + strings.Repeat("0", 64)
+		if _, _, err := client.DownloadChart(t.Context(), chartUrl, "1.0.0@"+wrong); err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+			t.Errorf("expected digest mismatch error, got %v", err)
+		}
+	})
+
+	t.Run("refresh replaces changed content", func(t *testing.T) {
+		refreshing, err := NewClient(WithCacheDir(cacheDir), WithRefresh(true))
+		if err != nil {
+			t.Fatalf("failed to create client: %v", err)
+		}
+
+		chartDir, name, err := refreshing.DownloadChart(t.Context(), chartUrl, "1.0.0")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if name == originalName {
+			t.Fatalf("expected refreshed chart to replace %s", originalName)
+		}
+
+		values, err := refreshing.ReadValuesFromChart(chartDir, name)
+		if err != nil || !strings.Contains(values, "replicaCount: 3") {
+			t.Errorf("expected refreshed values, got %q (err: %v)", values, err)
+		}
+
+		entries := ListCacheEntries(cacheDir)
+		if len(entries) != 1 || entries[0].Digest != republishedDigest {
+			t.Errorf("expected a single entry with digest %s, got %+v", republishedDigest, entries)
+		}
+		if _, err := os.Stat(filepath.Join(cacheDir, originalName)); !os.IsNotExist(err) {
+			t.Errorf("expected stale chart directory %s to be removed", originalName)
+		}
+	})
+
+	t.Run("pinned digest is served from cache", func(t *testing.T) {
+		server.Close()
+
+		_, name, err := client.DownloadChart(t.Context(), chartUrl, "@"+republishedDigest)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.HasSuffix(name, shortDigest(republishedDigest)) {
+			t.Errorf("expected chart named after digest %s, got %s", republishedDigest, name)
+		}
+	})
+}
+
+func TestOfflineClient(t *testing.T) {
+	t.Parallel()
+
+	archiveDir := t.TempDir()
+	packageTestChart(t, archiveDir, "app", "1.0.0")
+	server := httptest.NewServer(http.FileServer(http.Dir(archiveDir)))
+	chartUrl := server.URL + "/app-1.0.0.tgz"
+	cacheDir := t.TempDir()
+
+	online, err := NewClient(WithCacheDir(cacheDir))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	if _, _, err := online.DownloadChart(t.Context(), chartUrl, "1.0.0"); err != nil {
+		t.Fatalf("failed to download chart: %v", err)
+	}
+	server.Close()
+
+	client, err := NewClient(WithCacheDir(cacheDir), WithOffline(true))
+	if err != nil {
+		t.Fatalf("failed to create offline client: %v", err)
+	}
+
+	hc := client.(*helmClient)
+	if hc.registryTransport != nil {
+		t.Error("offline client must not create a registry client")
+	}
+
+	chartDir, chartName, err := client.DownloadChart(t.Context(), chartUrl, "1.0.0")
+	if err != nil {
+		t.Fatalf("expected cached chart to be served offline: %v", err)
+	}
+
+	manifest, err := client.RenderTemplate(t.Context(), chartDir, chartName, "")
+	if err != nil {
+		t.Fatalf("failed to render offline: %v", err)
+	}
+	if _, ok := manifest["Deployment_helmhound-render-app"]; !ok {
+		t.Errorf("expected rendered deployment, got keys %v", reflect.ValueOf(manifest).MapKeys())
+	}
+
+	_, _, err = client.DownloadChart(t.Context(), chartUrl, "2.0.0")
+	if err == nil {
+		t.Fatal("expected error for uncached chart in offline mode")
+	}
+	if !strings.Contains(err.Error(), chartUrl) || !strings.Contains(err.Error(), "2.0.0") {
+		t.Errorf("expected error to name the chart and version, got %v", err)
+	}
+
+	if _, err := NewClient(WithCacheDir(cacheDir), WithOffline(true), WithRefresh(true)); err == nil {
+		t.Error("expected error when combining offline mode and refresh")
+	}
+}
+
+func TestClientIgnoresKubeconfig(t *testing.T) {
+	// Not parallel: the environment is process wide
+	brokenKubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := os.WriteFile(brokenKubeconfig, []byte("this is: [not a kubeconfig"), 0644); err != nil {
+		t.Fatalf("failed to write kubeconfig: %v", err)
+	}
+	t.Setenv("KUBECONFIG", brokenKubeconfig)
+	t.Setenv("HELM_DRIVER", "no-such-driver")
+	t.Setenv("HELM_NAMESPACE", "team-a")
+
+	archiveDir := t.TempDir()
+	packageTestChart(t, archiveDir, "app", "1.0.0")
+	server := httptest.NewServer(http.FileServer(http.Dir(archiveDir)))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(WithCacheDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	hc := client.(*helmClient)
+	if hc.actionConfig.RESTClientGetter != nil || hc.actionConfig.KubeClient != nil {
+		t.Error("client must not initialize the kube configuration")
+	}
+	if hc.namespace != "team-a" {
+		t.Errorf("expected namespace from HELM_NAMESPACE, got %s", hc.namespace)
+	}
+
+	chartDir, chartName, err := client.DownloadChart(t.Context(), server.URL+"/app-1.0.0.tgz", "1.0.0")
+	if err != nil {
+		t.Fatalf("failed to download chart: %v", err)
+	}
+	if _, err := client.RenderTemplate(t.Context(), chartDir, chartName, ""); err != nil {
+		t.Fatalf("failed to render without a kubeconfig: %v", err)
+	}
+
+	if _, err := NewClient(WithCacheDir(t.TempDir()), WithClusterValidation(true)); err == nil {
+		t.Error("expected cluster validation to fail with a broken kubeconfig")
+	}
+}
+
+// newFakeAPIServer serves the discovery endpoints needed to render a Deployment against a cluster
+func newFakeAPIServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	responses := map[string]string{
+		"/version": `{"major":"1","minor":"31","gitVersion":"v1.31.0"}`,
+		"/api":     `{"kind":"APIVersions","versions":["v1"]}`,
+		"/apis":    `{"kind":"APIGroupList","apiVersion":"v1","groups":[{"name":"apps","versions":[{"groupVersion":"apps/v1","version":"v1"}],"preferredVersion":{"groupVersion":"apps/v1","version":"v1"}}]}`,
+		"/api/v1":  `{"kind":"APIResourceList","groupVersion":"v1","resources":[{"name":"secrets","namespaced":true,"kind":"Secret","verbs":["get","list","create"]}]}`,
+		"/apis/apps/v1": `{"kind":"APIResourceList","groupVersion":"apps/v1","resources":[` +
+			`{"name":"deployments","namespaced":true,"kind":"Deployment","verbs":["get","list","create"]}]}`,
+		"/openapi/v3": `{"paths":{"apis/apps/v1":{"serverRelativeURL":"/openapi/v3/apis/apps/v1"}}}`,
+		// Advertising server-side field validation makes the client skip OpenAPI v2 schema validation
+		"/openapi/v3/apis/apps/v1": `{"openapi":"3.0.0","info":{"title":"fake","version":"v1"},"paths":{` +
+			`"/apis/apps/v1/namespaces/{namespace}/deployments/{name}":{"patch":{` +
+			`"x-kubernetes-group-version-kind":{"group":"apps","kind":"Deployment","version":"v1"},` +
+			`"parameters":[{"name":"fieldValidation","in":"query","schema":{"type":"string"}}]}}}}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClusterValidation(t *testing.T) {
+	// Not parallel: the environment is process wide
+	apiServer := newFakeAPIServer(t)
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	kubeconfigYaml := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+  - name: fake
+    cluster:
+      server: %s
+contexts:
+  - name: fake
+    context:
+      cluster: fake
+      namespace: team-b
+current-context: fake
+`, apiServer.URL)
+	if err := os.WriteFile(kubeconfig, []byte(kubeconfigYaml), 0644); err != nil {
+		t.Fatalf("failed to write kubeconfig: %v", err)
+	}
+	t.Setenv("KUBECONFIG", kubeconfig)
+	t.Setenv("HELM_DRIVER", "memory")
+
+	archiveDir := t.TempDir()
+	packageTestChart(t, archiveDir, "app", "1.0.0")
+	chartServer := httptest.NewServer(http.FileServer(http.Dir(archiveDir)))
+	t.Cleanup(chartServer.Close)
+
+	client, err := NewClient(WithCacheDir(t.TempDir()), WithClusterValidation(true))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	if ns := client.(*helmClient).namespace; ns != "team-b" {
+		t.Errorf("expected namespace from the kubeconfig context, got %s", ns)
+	}
+
+	chartDir, chartName, err := client.DownloadChart(t.Context(), chartServer.URL+"/app-1.0.0.tgz", "1.0.0")
+	if err != nil {
+		t.Fatalf("failed to download chart: %v", err)
+	}
+	manifest, err := client.RenderTemplate(t.Context(), chartDir, chartName, "")
+	if err != nil {
+		t.Fatalf("failed to render against the cluster: %v", err)
+	}
+	if _, ok := manifest["Deployment_helmhound-render-app"]; !ok {
+		t.Errorf("expected rendered deployment, got keys %v", reflect.ValueOf(manifest).MapKeys())
+	}
+}
+
+func TestReleaseBaseline(t *testing.T) {
+	t.Parallel()
+
+	archiveDir := t.TempDir()
+	packageTestChart(t, archiveDir, "app", "1.0.0")
+	server := httptest.NewServer(http.FileServer(http.Dir(archiveDir)))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(WithCacheDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	chartDir, chartName, err := client.DownloadChart(t.Context(), server.URL+"/app-1.0.0.tgz", "1.0.0")
+	if err != nil {
+		t.Fatalf("failed to download chart: %v", err)
+	}
+
+	// A release deployed with overridden values, as stored by helm; values round-trip through JSON
+	deployedManifest := `---
+# Source: app/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web-app
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+        - name: app
+          image: registry.internal/nginx
+`
+	mem := driver.NewMemory()
+	mem.SetNamespace("prod")
+	releases := storage.Init(mem)
+	err = releases.Create(&release.Release{
+		Name:      "web",
+		Namespace: "prod",
+		Version:   1,
+		Info:      &release.Info{Status: release.StatusDeployed},
+		Config: map[string]interface{}{
+			"replicaCount": float64(3),
+			"image":        map[string]interface{}{"repository": "registry.internal/nginx"},
+		},
+		Chart:    &chart.Chart{Metadata: &chart.Metadata{Name: "app", Version: "1.0.0"}},
+		Manifest: deployedManifest,
+	})
+	if err != nil {
+		t.Fatalf("failed to store release: %v", err)
+	}
+
+	hc := client.(*helmClient)
+	hc.actionConfig.Releases = releases
+	hc.actionConfig.KubeClient = &kubefake.PrintingKubeClient{Out: io.Discard}
+
+	if err := hc.loadRelease("missing"); err == nil {
+		t.Error("expected error for a release that does not exist")
+	}
+	if err := hc.loadRelease("web"); err != nil {
+		t.Fatalf("failed to load release: %v", err)
+	}
+	if values := client.ReleaseValues(); values["replicaCount"] != 3.0 {
+		t.Errorf("expected the release values, got %v", values)
+	}
+
+	original, err := client.RenderTemplate(t.Context(), chartDir, chartName, "")
+	if err != nil {
+		t.Fatalf("failed to get release manifest: %v", err)
+	}
+	modified, applied, err := client.RenderTemplateWithModifiedValue(t.Context(), chartDir, chartName, "replicaCount", "")
+	if err != nil {
+		t.Fatalf("failed to render modified release: %v", err)
+	}
+	// The release values are decoded from JSON, so the original value is a float64
+	if applied.Strategy != MutationIncrement || applied.Original != 3.0 || applied.Modified != 4 {
+		t.Errorf("expected the release value 3 to be incremented, got %v -> %v", applied.Original, applied.Modified)
+	}
+
+	// Local values or another chart version would show up as impact of the mutation
+	valuesFile := filepath.Join(t.TempDir(), "values.yaml")
+	if err := os.WriteFile(valuesFile, []byte("replicaCount: 5\n"), 0644); err != nil {
+		t.Fatalf("failed to write values file: %v", err)
+	}
+	if _, _, err := client.RenderTemplateWithModifiedValue(t.Context(), chartDir, chartName, "replicaCount", valuesFile); err == nil {
+		t.Error("expected error for a values file combined with a release")
+	}
+	deployed := hc.release
+	drifted := *deployed
+	drifted.Chart = &chart.Chart{Metadata: &chart.Metadata{Name: "app", Version: "0.9.0"}}
+	hc.release = &drifted
+	if _, err := client.RenderTemplate(t.Context(), chartDir, chartName, ""); err == nil || !strings.Contains(err.Error(), "0.9.0") {
+		t.Errorf("expected error for a release deployed with another chart version, got %v", err)
+	}
+	hc.release = deployed
+
+	tests := []struct {
+		name         string
+		manifest     map[string]interface{}
+		wantReplicas int
+	}{
+		{name: "baseline is the deployed manifest", manifest: original, wantReplicas: 3},
+		{name: "modified render starts from the release values", manifest: modified, wantReplicas: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			deployment, ok := tt.manifest["Deployment_web-app"].(map[string]interface{})
+			if !ok {
+				t.Fatalf("expected deployment named after the release, got keys %v", reflect.ValueOf(tt.manifest).MapKeys())
+			}
+			spec := deployment["spec"].(map[string]interface{})
+			if spec["replicas"] != tt.wantReplicas {
+				t.Errorf("expected %d replicas, got %v", tt.wantReplicas, spec["replicas"])
+			}
+			containers := spec["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})
+			if image := containers[0].(map[string]interface{})["image"]; image != "registry.internal/nginx" {
+				t.Errorf("expected image from the release values, got %v", image)
+			}
+		})
+	}
+}
+
+func TestRenderTimeout(t *testing.T) {
+	t.Parallel()
+
+	// A template that renders for about a second, well beyond the deadline, but then finishes so that the
+	// abandoned render does not keep spinning until the test binary exits
+	runaway := `{{- range until 2000 }}{{ range until 10000 }}{{ end }}{{ end }}`
+	c := &chart.Chart{
+		Metadata:  &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "runaway", Version: "1.0.0"},
+		Raw:       []*chart.File{{Name: chartutil.ValuesfileName, Data: []byte("replicaCount: 1\n")}},
+		Templates: []*chart.File{{Name: "templates/runaway.yaml", Data: []byte(runaway)}},
+	}
+	chartDir := t.TempDir()
+	if err := chartutil.SaveDir(c, chartDir); err != nil {
+		t.Fatalf("failed to write chart: %v", err)
+	}
+
+	client, err := NewClient(WithCacheDir(t.TempDir()), WithOffline(true))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = client.RenderTemplate(ctx, chartDir, "runaway", "")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the render to be abandoned at the deadline, took %v", elapsed)
+	}
+}
+
+func TestDownloadChartTimeout(t *testing.T) {
+	t.Parallel()
+
+	// The chart repository accepts the request but does not answer until released
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-release
+		http.NotFound(w, req)
+	}))
+	t.Cleanup(server.Close)
+
+	cacheDir := t.TempDir()
+	client, err := NewClient(WithCacheDir(cacheDir))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+
+	if _, _, err := client.DownloadChart(ctx, server.URL+"/app-1.0.0.tgz", "1.0.0"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// The abandoned pull removes its download directory once the repository answers
+	close(release)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		leftovers, _ := filepath.Glob(filepath.Join(cacheDir, ".download-*"))
+		if len(leftovers) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("download directories were not cleaned up: %v", leftovers)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
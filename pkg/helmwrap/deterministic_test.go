@@ -0,0 +1,135 @@
+package helmwrap
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+func TestStubTemplate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "random string",
+			input:    `password: {{ randAlphaNum 16 | b64enc }}`,
+			expected: `password: {{ printf "%0[2]*[1]d" 0 16 | b64enc }}`,
+		},
+		{
+			name:     "piped length",
+			input:    `password: {{ .Values.length | randAlpha }}`,
+			expected: `password: {{ .Values.length | printf "%0[2]*[1]d" 0 }}`,
+		},
+		{
+			name:     "function as operand",
+			input:    `date: {{ date "2006-01-02" now }}`,
+			expected: `date: {{ date "2006-01-02" (toDate "2006-01-02" "1970-01-01") }}`,
+		},
+		{
+			name:     "nested pipeline",
+			input:    "{{- $ca := (genCA \"my-ca\" 365) }}\ncert: {{ $ca.Cert | b64enc }}",
+			expected: "{{- $ca := (dict \"Cert\" " + strconv.Quote(stubCertificate) + " \"Key\" " + strconv.Quote(stubPrivateKey) + " \"my-ca\" 365) }}\ncert: {{ $ca.Cert | b64enc }}",
+		},
+		{
+			name:     "inside define, if and range",
+			input:    "{{ define \"id\" }}{{ uuidv4 }}{{ end }}\n{{ if .Values.a }}{{ range .Values.b }}{{ randInt 1 10 }}{{ end }}{{ end }}",
+			expected: "{{ define \"id\" }}{{ print " + strconv.Quote(stubUUID) + " }}{{ end }}\n{{ if .Values.a }}{{ range .Values.b }}{{ min 1 10 }}{{ end }}{{ end }}",
+		},
+		{
+			name:     "names in text, strings and fields are kept",
+			input:    `now: {{ .Values.now }} {{ "randAlphaNum" }} {{ $now := 1 }}{{ $now }}`,
+			expected: `now: {{ .Values.now }} {{ "randAlphaNum" }} {{ $now := 1 }}{{ $now }}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := stubTemplate("test.yaml", tt.input)
+			if err != nil {
+				t.Fatalf("failed to stub template: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("stubTemplate() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDeterministicRender(t *testing.T) {
+	t.Parallel()
+
+	secret := `apiVersion: v1
+kind: Secret
+metadata:
+  name: random
+  annotations:
+    generated-at: {{ now | date "2006-01-02T15:04:05" | quote }}
+    id: {{ uuidv4 | quote }}
+data:
+  password: {{ randAlphaNum 24 | b64enc | quote }}
+  pin: {{ randInt 1000 9999 | toString | b64enc | quote }}
+  {{- $ca := genCA "random-ca" 365 }}
+  {{- $cert := genSignedCert "random" nil (list "random.example.com") 365 $ca }}
+  tls.crt: {{ $cert.Cert | b64enc | quote }}
+  tls.key: {{ genPrivateKey "ecdsa" | b64enc | quote }}
+`
+	sub := &chart.Chart{
+		Metadata:  &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "sub", Version: "1.0.0"},
+		Templates: []*chart.File{{Name: "templates/configmap.yaml", Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: sub\ndata:\n  token: {{ randAscii 8 | quote }}\n")}},
+	}
+	c := &chart.Chart{
+		Metadata:  &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "random", Version: "1.0.0"},
+		Raw:       []*chart.File{{Name: chartutil.ValuesfileName, Data: []byte("replicaCount: 1\n")}},
+		Templates: []*chart.File{{Name: "templates/secret.yaml", Data: []byte(secret)}},
+	}
+	c.AddDependency(sub)
+	chartDir := t.TempDir()
+	if err := chartutil.SaveDir(c, chartDir); err != nil {
+		t.Fatalf("failed to write chart: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		deterministic bool
+		wantEqual     bool
+	}{
+		{name: "random by default", deterministic: false, wantEqual: false},
+		{name: "deterministic", deterministic: true, wantEqual: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, err := NewClient(WithCacheDir(t.TempDir()), WithOffline(true), WithDeterministicRender(tt.deterministic))
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+
+			first, err := client.RenderTemplate(t.Context(), chartDir, "random", "")
+			if err != nil {
+				t.Fatalf("failed to render: %v", err)
+			}
+			second, err := client.RenderTemplate(t.Context(), chartDir, "random", "")
+			if err != nil {
+				t.Fatalf("failed to render: %v", err)
+			}
+
+			if equal := reflect.DeepEqual(first, second); equal != tt.wantEqual {
+				t.Errorf("expected equal renders to be %v, got %v:\n%v\n%v", tt.wantEqual, equal, first, second)
+			}
+			if _, ok := first["ConfigMap_sub"]; !ok {
+				t.Errorf("expected the subchart to be rendered, got %v", first)
+			}
+		})
+	}
+}
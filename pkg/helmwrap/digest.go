@@ -0,0 +1,52 @@
+package helmwrap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// digestPattern matches a sha256 content digest such as "sha256:3b0c44298fc1..."
+var digestPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// parseChartVersion splits a --chart-version value of the form "1.2.3", "@sha256:..." or "1.2.3@sha256:..."
+// into the version constraint and the pinned digest
+func parseChartVersion(chartVersion string) (string, string, error) {
+	version, digest, pinned := strings.Cut(chartVersion, "@")
+	if !pinned {
+		return chartVersion, "", nil
+	}
+
+	if !digestPattern.MatchString(digest) {
+		return "", "", fmt.Errorf("invalid digest %q: expected sha256:<64 hex characters>", digest)
+	}
+	return version, digest, nil
+}
+
+// fileDigest returns the sha256 digest of the file at path in "sha256:<hex>" form
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// shortDigest returns the first 12 hex characters of a digest for use in directory names
+func shortDigest(digest string) string {
+	hexDigest := strings.TrimPrefix(digest, "sha256:")
+	if len(hexDigest) > 12 {
+		return hexDigest[:12]
+	}
+	return hexDigest
+}
@@ -0,0 +1,42 @@
+package helmwrap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseChartVersion(t *testing.T) {
+	t.Parallel()
+
+	digest := "sha256:" + strings.Repeat("ab", 32)
+
+	tests := []struct {
+		name        string
+		input       string
+		wantVersion string
+		wantDigest  string
+		wantErr     bool
+	}{
+		{name: "plain version", input: "1.2.3", wantVersion: "1.2.3"},
+		{name: "version constraint", input: "^1.2", wantVersion: "^1.2"},
+		{name: "digest only", input: "@" + digest, wantDigest: digest},
+		{name: "version and digest", input: "1.2.3@" + digest, wantVersion: "1.2.3", wantDigest: digest},
+		{name: "unsupported algorithm", input: "@sha512:abcd", wantErr: true},
+		{name: "short digest", input: "@sha256:abcd", wantErr: true},
+		{name: "uppercase hex", input: "@sha256:" + strings.Repeat("AB", 32), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			version, digest, err := parseChartVersion(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseChartVersion(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if version != tt.wantVersion || digest != tt.wantDigest {
+				t.Errorf("parseChartVersion(%q) = (%q, %q), want (%q, %q)", tt.input, version, digest, tt.wantVersion, tt.wantDigest)
+			}
+		})
+	}
+}
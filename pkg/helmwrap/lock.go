@@ -0,0 +1,73 @@
+package helmwrap
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// cacheLock is an exclusive, cross-process lock on a helmhound cache directory
+type cacheLock struct {
+	file *os.File
+}
+
+// lockCache blocks until this process holds the exclusive lock on helmhoundDir
+func lockCache(helmhoundDir string) (*cacheLock, error) {
+	lockPath := filepath.Join(helmhoundDir, "cache.lock")
+
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache lock file: %v", err)
+	}
+
+	if err := lockFile(file); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to lock cache directory: %v", err)
+	}
+
+	return &cacheLock{file: file}, nil
+}
+
+// tryLockCache takes the exclusive lock on helmhoundDir if no other process holds it.
+// It returns a nil lock if the lock is held elsewhere.
+func tryLockCache(helmhoundDir string) (*cacheLock, error) {
+	lockPath := filepath.Join(helmhoundDir, "cache.lock")
+
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache lock file: %v", err)
+	}
+
+	locked, err := tryLockFile(file)
+	if err != nil || !locked {
+		_ = file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock cache directory: %v", err)
+		}
+		return nil, nil
+	}
+
+	return &cacheLock{file: file}, nil
+}
+
+// Unlock releases the lock
+func (l *cacheLock) Unlock() {
+	_ = unlockFile(l.file)
+	_ = l.file.Close()
+}
+
+// updateCacheFile runs fn on the current cache file while holding the cache lock and saves the result
+func updateCacheFile(helmhoundDir string, fn func(cacheFile *CacheFile) error) error {
+	lock, err := lockCache(helmhoundDir)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	cacheFile := loadCacheFile(helmhoundDir)
+	if err := fn(&cacheFile); err != nil {
+		return err
+	}
+
+	return saveCacheFile(helmhoundDir, cacheFile)
+}
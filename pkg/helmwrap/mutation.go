@@ -0,0 +1,84 @@
+package helmwrap
+
+import (
+	"context"
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// MutationStrategy names how RenderTemplateWithModifiedValue modifies a value of a given type
+type MutationStrategy string
+
+const (
+	// MutationPrefix prefixes a string with "helmhound-test-"
+	MutationPrefix MutationStrategy = "prefix"
+	// MutationIncrement adds one to a number
+	MutationIncrement MutationStrategy = "increment"
+	// MutationToggle negates a boolean
+	MutationToggle MutationStrategy = "toggle"
+	// MutationAppendElement appends "helmhound-test-element" to a list
+	MutationAppendElement MutationStrategy = "append-element"
+	// MutationAddKey adds the key "helmhound-test-key" to a map
+	MutationAddKey MutationStrategy = "add-key"
+)
+
+// mutationStrategies maps value types to the strategy modifyValueAtPath applies to them
+var mutationStrategies = map[ValueType]MutationStrategy{
+	ValueTypeString: MutationPrefix,
+	ValueTypeInt:    MutationIncrement,
+	ValueTypeBool:   MutationToggle,
+	ValueTypeSlice:  MutationAppendElement,
+	ValueTypeMap:    MutationAddKey,
+}
+
+// AppliedMutation describes how RenderTemplateWithModifiedValue modified a value
+type AppliedMutation struct {
+	Path     string
+	Strategy MutationStrategy
+	// Original and Modified are the value before and after the modification
+	Original interface{}
+	Modified interface{}
+	// BaselineValues and ModifiedValues are the values of the chart before and after the modification,
+	// coalesced by Helm so that they include subchart defaults and the globals propagated to subcharts
+	BaselineValues map[string]interface{}
+	ModifiedValues map[string]interface{}
+}
+
+// newAppliedMutation describes the modification of the value at path from the baseline values to the modified values
+// of the loaded chart. Coalescing the values of large charts takes a while, so it stops early if ctx is done.
+func newAppliedMutation(ctx context.Context, loaded *chart.Chart, path string, valueType ValueType, baseline, modified map[string]interface{}) (*AppliedMutation, error) {
+	original, err := getValueAtPath(baseline, path)
+	if err != nil {
+		return nil, err
+	}
+	modifiedValue, err := getValueAtPath(modified, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	baselineValues, err := chartutil.CoalesceValues(loaded, baseline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to coalesce values: %v", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	modifiedValues, err := chartutil.CoalesceValues(loaded, modified)
+	if err != nil {
+		return nil, fmt.Errorf("failed to coalesce modified values: %v", err)
+	}
+
+	return &AppliedMutation{
+		Path:           path,
+		Strategy:       mutationStrategies[valueType],
+		Original:       original,
+		Modified:       modifiedValue,
+		BaselineValues: baselineValues.AsMap(),
+		ModifiedValues: modifiedValues.AsMap(),
+	}, nil
+}
@@ -0,0 +1,109 @@
+package helmwrap
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+func TestNewAppliedMutation(t *testing.T) {
+	t.Parallel()
+
+	sub := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "sub", Version: "1.0.0"},
+		Raw:      []*chart.File{{Name: chartutil.ValuesfileName, Data: []byte("port: 80\n")}},
+		Values:   map[string]interface{}{"port": 80},
+	}
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "app", Version: "1.0.0"},
+		Raw:      []*chart.File{{Name: chartutil.ValuesfileName, Data: []byte("global:\n  registry: docker.io\nreplicaCount: 1\n")}},
+	}
+	c.AddDependency(sub)
+	chartDir := t.TempDir()
+	if err := chartutil.SaveDir(c, chartDir); err != nil {
+		t.Fatalf("failed to write chart: %v", err)
+	}
+	loaded, err := loader.Load(filepath.Join(chartDir, "app"))
+	if err != nil {
+		t.Fatalf("failed to load chart: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		path         string
+		valueType    ValueType
+		wantStrategy MutationStrategy
+		wantOriginal interface{}
+		wantModified interface{}
+		wantSubchart interface{}
+	}{
+		{
+			name:         "global propagated to the subchart",
+			path:         "global.registry",
+			valueType:    ValueTypeString,
+			wantStrategy: MutationPrefix,
+			wantOriginal: "docker.io",
+			wantModified: "helmhound-test-docker.io",
+			wantSubchart: map[string]interface{}{"port": 80.0, "global": map[string]interface{}{"registry": "helmhound-test-docker.io"}},
+		},
+		{
+			name:         "value of the parent chart",
+			path:         "replicaCount",
+			valueType:    ValueTypeInt,
+			wantStrategy: MutationIncrement,
+			wantOriginal: 1,
+			wantModified: 2,
+			wantSubchart: map[string]interface{}{"port": 80.0, "global": map[string]interface{}{"registry": "docker.io"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			baseline := map[string]interface{}{
+				"global":       map[string]interface{}{"registry": "docker.io"},
+				"replicaCount": 1,
+			}
+			modified, err := modifyValueAtPath(baseline, tt.path, tt.valueType)
+			if err != nil {
+				t.Fatalf("failed to modify value: %v", err)
+			}
+
+			applied, err := newAppliedMutation(t.Context(), loaded, tt.path, tt.valueType, baseline, modified)
+			if err != nil {
+				t.Fatalf("failed to describe the mutation: %v", err)
+			}
+			if applied.Strategy != tt.wantStrategy {
+				t.Errorf("expected strategy %s, got %s", tt.wantStrategy, applied.Strategy)
+			}
+			if !reflect.DeepEqual(applied.Original, tt.wantOriginal) || !reflect.DeepEqual(applied.Modified, tt.wantModified) {
+				t.Errorf("expected %v -> %v, got %v -> %v", tt.wantOriginal, tt.wantModified, applied.Original, applied.Modified)
+			}
+			if got := applied.ModifiedValues["sub"]; !reflect.DeepEqual(got, tt.wantSubchart) {
+				t.Errorf("expected subchart values %v, got %v", tt.wantSubchart, got)
+			}
+		})
+	}
+}
+
+func TestNewAppliedMutationCanceled(t *testing.T) {
+	t.Parallel()
+
+	loaded := &chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "app", Version: "1.0.0"}}
+	values := map[string]interface{}{"replicaCount": 1}
+	modified := map[string]interface{}{"replicaCount": 2}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if _, err := newAppliedMutation(ctx, loaded, "replicaCount", ValueTypeInt, values, modified); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package helmwrap
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/registry"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/credentials"
+)
+
+// RegistryOptions configures how charts are pulled from OCI registries and chart repositories
+type RegistryOptions struct {
+	// ConfigFile is the registry credentials file (default: helm's registry/config.json)
+	ConfigFile string
+	// Username and Password are used instead of the credentials file when Username is set
+	Username string
+	Password string
+	// CAFile verifies registry certificates with the given CA bundle
+	CAFile                string
+	InsecureSkipTLSVerify bool
+	// PlainHTTP talks to the registry over HTTP instead of HTTPS
+	PlainHTTP bool
+}
+
+// WithRegistryOptions configures authentication and transport security for chart downloads
+func WithRegistryOptions(opts RegistryOptions) ClientOption {
+	return func(c *helmClient) {
+		c.registryOptions = opts
+	}
+}
+
+// newRegistryClient creates a helm registry client whose requests are canceled with ctx and returns it together
+// with its authorizer, which is also used to resolve manifest digests since helm's Resolve does not authenticate
+func (c *helmClient) newRegistryClient(ctx context.Context) (*registry.Client, *auth.Client, error) {
+	httpClient := &http.Client{Transport: &contextTransport{ctx: ctx, base: c.registryTransport}}
+
+	authorizer := auth.Client{
+		Client:     httpClient,
+		Cache:      auth.NewCache(),
+		Credential: c.registryCredential,
+	}
+
+	opts := c.registryOptions
+	clientOpts := []registry.ClientOption{
+		registry.ClientOptHTTPClient(httpClient),
+		registry.ClientOptAuthorizer(authorizer),
+		registry.ClientOptEnableCache(true),
+	}
+	if opts.ConfigFile != "" {
+		clientOpts = append(clientOpts, registry.ClientOptCredentialsFile(opts.ConfigFile))
+	}
+	if opts.Username != "" {
+		clientOpts = append(clientOpts, registry.ClientOptBasicAuth(opts.Username, opts.Password))
+	}
+	if opts.PlainHTTP {
+		clientOpts = append(clientOpts, registry.ClientOptPlainHTTP())
+	}
+
+	registryClient, err := registry.NewClient(clientOpts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return registryClient, &authorizer, nil
+}
+
+// contextTransport sends every request with ctx, since helm's registry client does not take a context
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(t.ctx))
+}
+
+// newRegistryTransport returns an HTTP transport trusting opts.CAFile and honoring opts.InsecureSkipTLSVerify
+func newRegistryTransport(opts RegistryOptions) (http.RoundTripper, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if opts.CAFile != "" || opts.InsecureSkipTLSVerify {
+		tlsConfig := &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: opts.InsecureSkipTLSVerify,
+		}
+		if opts.CAFile != "" {
+			caPEM, err := os.ReadFile(opts.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA file: %v", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(caPEM) {
+				return nil, fmt.Errorf("no certificates found in CA file %s", opts.CAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return transport, nil
+}
+
+// registryCredential returns the credential function of the authorizer. Explicit credentials win;
+// otherwise credentials are looked up in the registry config file, falling back to the docker config like helm does.
+func registryCredential(opts RegistryOptions) (auth.CredentialFunc, error) {
+	if opts.Username != "" {
+		cred := auth.Credential{Username: opts.Username, Password: opts.Password}
+		return func(context.Context, string) (auth.Credential, error) {
+			return cred, nil
+		}, nil
+	}
+
+	configFile := opts.ConfigFile
+	if configFile == "" {
+		configFile = helmpath.ConfigPath(registry.CredentialsFileBasename)
+	}
+
+	storeOptions := credentials.StoreOptions{DetectDefaultNativeStore: true}
+	store, err := credentials.NewStore(configFile, storeOptions)
+	if err != nil {
+		// helm leaves an empty config file behind after logging out of every registry
+		if !strings.Contains(err.Error(), "invalid config format: EOF") {
+			return nil, fmt.Errorf("failed to read registry config %s: %v", configFile, err)
+		}
+		if store, err = credentials.NewStore("", storeOptions); err != nil {
+			return nil, fmt.Errorf("failed to read registry config %s: %v", configFile, err)
+		}
+	}
+
+	var credentialStore credentials.Store = store
+	if dockerStore, err := credentials.NewStoreFromDocker(storeOptions); err == nil {
+		credentialStore = credentials.NewStoreWithFallbacks(store, dockerStore)
+	}
+
+	return credentials.Credential(credentialStore), nil
+}
+
+// resolveManifestDigest returns the OCI manifest digest the chart version's tag currently points at,
+// or an empty string if it cannot be resolved
+func (c *helmClient) resolveManifestDigest(ctx context.Context, authorizer *auth.Client, chartUrl, version string) string {
+	// OCI tags cannot contain "+", which helm replaces with "_" when pushing
+	ref := strings.TrimPrefix(chartUrl, registry.OCIScheme+"://") + ":" + strings.ReplaceAll(version, "+", "_")
+
+	repo, err := remote.NewRepository(ref)
+	if err == nil {
+		repo.Client = authorizer
+		repo.PlainHTTP = c.registryOptions.PlainHTTP
+
+		desc, resolveErr := repo.Resolve(ctx, repo.Reference.Reference)
+		if resolveErr == nil {
+			return desc.Digest.String()
+		}
+		err = resolveErr
+	}
+
+	c.logger.Warn("failed to resolve manifest digest", "reference", ref, "error", err)
+	return ""
+}
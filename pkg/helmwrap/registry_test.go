@@ -0,0 +1,256 @@
+package helmwrap
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testRegistry is a minimal in-process OCI registry serving helm charts, optionally behind basic auth
+type testRegistry struct {
+	username  string
+	password  string
+	manifests map[string][]byte // keyed by "repository:tag" and "repository@digest"
+	blobs     map[string][]byte // keyed by digest
+}
+
+func newTestRegistry(username, password string) *testRegistry {
+	return &testRegistry{
+		username:  username,
+		password:  password,
+		manifests: make(map[string][]byte),
+		blobs:     make(map[string][]byte),
+	}
+}
+
+// pushChart stores the chart archive under repository:version and returns the manifest digest
+func (r *testRegistry) pushChart(t *testing.T, repository, archivePath, name, version string) string {
+	t.Helper()
+
+	archive, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatalf("failed to read chart archive: %v", err)
+	}
+	config := []byte(fmt.Sprintf(`{"apiVersion":"v2","name":%q,"version":%q}`, name, version))
+
+	manifest, err := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"config":        r.addBlob("application/vnd.cncf.helm.config.v1+json", config),
+		"layers":        []any{r.addBlob("application/vnd.cncf.helm.chart.content.v1.tar+gzip", archive)},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal manifest: %v", err)
+	}
+
+	digest := testDigest(manifest)
+	r.manifests[repository+":"+version] = manifest
+	r.manifests[repository+"@"+digest] = manifest
+	return digest
+}
+
+// addBlob stores content and returns its OCI descriptor
+func (r *testRegistry) addBlob(mediaType string, content []byte) map[string]any {
+	digest := testDigest(content)
+	r.blobs[digest] = content
+	return map[string]any{"mediaType": mediaType, "digest": digest, "size": len(content)}
+}
+
+func (r *testRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.username != "" {
+		if username, password, ok := req.BasicAuth(); !ok || username != r.username || password != r.password {
+			w.Header().Set("WWW-Authenticate", `Basic realm="helmhound-test"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	if path == "" {
+		return
+	}
+
+	var content []byte
+	var ok bool
+	var mediaType string
+	if i := strings.LastIndex(path, "/manifests/"); i >= 0 {
+		reference := path[i+len("/manifests/"):]
+		separator := ":"
+		if strings.HasPrefix(reference, "sha256:") {
+			separator = "@"
+		}
+		content, ok = r.manifests[path[:i]+separator+reference]
+		mediaType = "application/vnd.oci.image.manifest.v1+json"
+	} else if i := strings.LastIndex(path, "/blobs/"); i >= 0 {
+		content, ok = r.blobs[path[i+len("/blobs/"):]]
+		mediaType = "application/octet-stream"
+	}
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	w.Header().Set("Docker-Content-Digest", testDigest(content))
+	if req.Method != http.MethodHead {
+		_, _ = w.Write(content)
+	}
+}
+
+func testDigest(content []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(content))
+}
+
+func TestDownloadChartFromRegistry(t *testing.T) {
+	t.Parallel()
+
+	reg := newTestRegistry("helmhound", "s3cret")
+	manifestDigest := reg.pushChart(t, "charts/app", packageTestChart(t, t.TempDir(), "app", "1.0.0"), "app", "1.0.0")
+
+	plainServer := httptest.NewServer(reg)
+	t.Cleanup(plainServer.Close)
+	tlsServer := httptest.NewTLSServer(reg)
+	t.Cleanup(tlsServer.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0644); err != nil {
+		t.Fatalf("failed to write CA file: %v", err)
+	}
+
+	plainHost := strings.TrimPrefix(plainServer.URL, "http://")
+	tlsHost := strings.TrimPrefix(tlsServer.URL, "https://")
+
+	registryConfig := filepath.Join(t.TempDir(), "config.json")
+	auth := base64.StdEncoding.EncodeToString([]byte("helmhound:s3cret"))
+	if err := os.WriteFile(registryConfig, []byte(`{"auths":{"`+plainHost+`":{"auth":"`+auth+`"}}}`), 0644); err != nil {
+		t.Fatalf("failed to write registry config: %v", err)
+	}
+	emptyRegistryConfig := filepath.Join(t.TempDir(), "config.json")
+
+	tests := []struct {
+		name     string
+		host     string
+		version  string
+		opts     RegistryOptions
+		wantFail bool
+	}{
+		{
+			name:    "basic auth over plain http",
+			host:    plainHost,
+			version: "1.0.0",
+			opts:    RegistryOptions{ConfigFile: emptyRegistryConfig, Username: "helmhound", Password: "s3cret", PlainHTTP: true},
+		},
+		{
+			name:    "credentials from registry config",
+			host:    plainHost,
+			version: "1.0.0",
+			opts:    RegistryOptions{ConfigFile: registryConfig, PlainHTTP: true},
+		},
+		{
+			name:    "pinned manifest digest",
+			host:    plainHost,
+			version: "@" + manifestDigest,
+			opts:    RegistryOptions{ConfigFile: emptyRegistryConfig, Username: "helmhound", Password: "s3cret", PlainHTTP: true},
+		},
+		{
+			name:    "custom CA",
+			host:    tlsHost,
+			version: "1.0.0",
+			opts:    RegistryOptions{ConfigFile: emptyRegistryConfig, Username: "helmhound", Password: "s3cret", CAFile: caFile},
+		},
+		{
+			name:    "insecure skip tls verify",
+			host:    tlsHost,
+			version: "1.0.0",
+			opts:    RegistryOptions{ConfigFile: emptyRegistryConfig, Username: "helmhound", Password: "s3cret", InsecureSkipTLSVerify: true},
+		},
+		{
+			name:     "wrong password",
+			host:     plainHost,
+			version:  "1.0.0",
+			opts:     RegistryOptions{ConfigFile: emptyRegistryConfig, Username: "helmhound", Password: "wrong", PlainHTTP: true},
+			wantFail: true,
+		},
+		{
+			name:     "untrusted certificate",
+			host:     tlsHost,
+			version:  "1.0.0",
+			opts:     RegistryOptions{ConfigFile: emptyRegistryConfig, Username: "helmhound", Password: "s3cret"},
+			wantFail: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cacheDir := t.TempDir()
+			client, err := NewClient(WithCacheDir(cacheDir), WithRegistryOptions(tt.opts))
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+
+			chartUrl := "oci://" + tt.host + "/charts/app"
+			_, chartName, err := client.DownloadChart(t.Context(), chartUrl, tt.version)
+			if tt.wantFail {
+				if err == nil {
+					t.Fatal("expected download to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to download chart: %v", err)
+			}
+
+			entries := ListCacheEntries(cacheDir)
+			if len(entries) != 1 || entries[0].ChartName != chartName {
+				t.Fatalf("expected a single cache entry for %s, got %+v", chartName, entries)
+			}
+			if entries[0].ManifestDigest != manifestDigest {
+				t.Errorf("expected manifest digest %s, got %s", manifestDigest, entries[0].ManifestDigest)
+			}
+		})
+	}
+}
+
+func TestDownloadChartCanceled(t *testing.T) {
+	t.Parallel()
+
+	// The registry never answers, so only canceling the context can end the download
+	blocked := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		close(blocked)
+		<-req.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(WithCacheDir(t.TempDir()), WithRegistryOptions(RegistryOptions{ConfigFile: filepath.Join(t.TempDir(), "config.json"), PlainHTTP: true}))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	go func() {
+		<-blocked
+		cancel()
+	}()
+
+	chartUrl := "oci://" + strings.TrimPrefix(server.URL, "http://") + "/charts/app"
+	_, _, err = client.DownloadChart(ctx, chartUrl, "1.0.0")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
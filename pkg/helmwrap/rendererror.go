@@ -0,0 +1,75 @@
+package helmwrap
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RenderError is a failed chart render together with the template location Helm reported for it
+type RenderError struct {
+	// Template is the failing template file including the chart name, e.g. "app/templates/deployment.yaml".
+	// It is empty if Helm did not report a template.
+	Template string `json:"template,omitempty"`
+	// Line and Column locate the failing action in the template; they are zero if Helm did not report them
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+	// Message is the error without the location, e.g. the message passed to required or fail
+	Message string `json:"message"`
+	// Err is the error returned by Helm
+	Err error `json:"-"`
+}
+
+func (e *RenderError) Error() string {
+	return fmt.Sprintf("failed to render templates: %v", e.Err)
+}
+
+func (e *RenderError) Unwrap() error {
+	return e.Err
+}
+
+// Location returns the template location as "file:line:column", omitting unknown parts
+func (e *RenderError) Location() string {
+	location := e.Template
+	if e.Line > 0 {
+		location += ":" + strconv.Itoa(e.Line)
+		if e.Column > 0 {
+			location += ":" + strconv.Itoa(e.Column)
+		}
+	}
+	return location
+}
+
+var (
+	// executionErrorPattern matches errors of required and fail, e.g.
+	// "execution error at (app/templates/deployment.yaml:5:3): image.tag is required"
+	executionErrorPattern = regexp.MustCompile(`(?s)^(?:execution|parse) error at \(([^()]+?):(\d+)(?::(\d+))?\): (.*)$`)
+	// templateErrorPattern matches other template errors, e.g.
+	// "template: app/templates/deployment.yaml:5:3: executing \"...\" at <.Values.a.b>: nil pointer evaluating interface {}.b"
+	templateErrorPattern = regexp.MustCompile(`(?s)^template: ([^:]+):(\d+)(?::(\d+))?: (?:executing "[^"]*" at <.*?>: )?(.*)$`)
+	// yamlErrorPattern matches rendered templates that are not valid YAML, e.g.
+	// "YAML parse error on app/templates/deployment.yaml: error converting YAML to JSON: yaml: line 3: ..."
+	yamlErrorPattern = regexp.MustCompile(`(?s)^YAML parse error on ([^:]+): (.*)$`)
+)
+
+// newRenderError parses the template location and message out of the error of a Helm render
+func newRenderError(err error) *RenderError {
+	renderErr := &RenderError{Message: err.Error(), Err: err}
+
+	if m := executionErrorPattern.FindStringSubmatch(err.Error()); m != nil {
+		renderErr.Template, renderErr.Message = m[1], m[4]
+		renderErr.Line, _ = strconv.Atoi(m[2])
+		renderErr.Column, _ = strconv.Atoi(m[3])
+	} else if m := templateErrorPattern.FindStringSubmatch(err.Error()); m != nil {
+		renderErr.Template, renderErr.Message = m[1], m[4]
+		renderErr.Line, _ = strconv.Atoi(m[2])
+		renderErr.Column, _ = strconv.Atoi(m[3])
+	} else if m := yamlErrorPattern.FindStringSubmatch(err.Error()); m != nil {
+		// The YAML error's line refers to the rendered output, not to the template
+		renderErr.Template, renderErr.Message = m[1], m[2]
+	}
+
+	renderErr.Message = strings.TrimSpace(renderErr.Message)
+	return renderErr
+}
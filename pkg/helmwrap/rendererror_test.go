@@ -0,0 +1,90 @@
+package helmwrap
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewRenderError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		err          string
+		wantTemplate string
+		wantLine     int
+		wantColumn   int
+		wantMessage  string
+		wantLocation string
+	}{
+		{
+			name:         "required",
+			err:          "execution error at (app/templates/deployment.yaml:5:14): image.tag is required",
+			wantTemplate: "app/templates/deployment.yaml",
+			wantLine:     5,
+			wantColumn:   14,
+			wantMessage:  "image.tag is required",
+			wantLocation: "app/templates/deployment.yaml:5:14",
+		},
+		{
+			name:         "fail in a helper",
+			err:          "execution error at (app/templates/_helpers.tpl:2:3): unsupported mode",
+			wantTemplate: "app/templates/_helpers.tpl",
+			wantLine:     2,
+			wantColumn:   3,
+			wantMessage:  "unsupported mode",
+			wantLocation: "app/templates/_helpers.tpl:2:3",
+		},
+		{
+			name:         "parse error",
+			err:          "parse error at (app/templates/service.yaml:7): missing value for if",
+			wantTemplate: "app/templates/service.yaml",
+			wantLine:     7,
+			wantMessage:  "missing value for if",
+			wantLocation: "app/templates/service.yaml:7",
+		},
+		{
+			name:         "nil pointer",
+			err:          `template: app/templates/deployment.yaml:9:13: executing "app/templates/deployment.yaml" at <.Values.a.b>: nil pointer evaluating interface {}.b`,
+			wantTemplate: "app/templates/deployment.yaml",
+			wantLine:     9,
+			wantColumn:   13,
+			wantMessage:  "nil pointer evaluating interface {}.b",
+			wantLocation: "app/templates/deployment.yaml:9:13",
+		},
+		{
+			name:         "invalid yaml",
+			err:          "YAML parse error on app/templates/configmap.yaml: error converting YAML to JSON: yaml: line 3: mapping values are not allowed in this context",
+			wantTemplate: "app/templates/configmap.yaml",
+			wantMessage:  "error converting YAML to JSON: yaml: line 3: mapping values are not allowed in this context",
+			wantLocation: "app/templates/configmap.yaml",
+		},
+		{
+			name:        "unknown format",
+			err:         "chart requires kubeVersion: >=1.30.0",
+			wantMessage: "chart requires kubeVersion: >=1.30.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cause := errors.New(tt.err)
+			got := newRenderError(cause)
+
+			if got.Template != tt.wantTemplate || got.Line != tt.wantLine || got.Column != tt.wantColumn {
+				t.Errorf("expected %s:%d:%d, got %s:%d:%d", tt.wantTemplate, tt.wantLine, tt.wantColumn, got.Template, got.Line, got.Column)
+			}
+			if got.Message != tt.wantMessage {
+				t.Errorf("expected message %q, got %q", tt.wantMessage, got.Message)
+			}
+			if got.Location() != tt.wantLocation {
+				t.Errorf("expected location %q, got %q", tt.wantLocation, got.Location())
+			}
+			if !errors.Is(got, cause) {
+				t.Errorf("expected the render error to wrap the helm error")
+			}
+		})
+	}
+}
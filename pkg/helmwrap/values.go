@@ -2,6 +2,9 @@ package helmwrap
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+
 	"gopkg.in/yaml.v3"
 )
 
@@ -17,38 +20,267 @@ const (
 	ValueTypeUnknown
 )
 
-// ExtractValuePaths recursively extracts all possible paths from a YAML string.
+// ExtractValuePaths extracts all possible paths from a YAML string in source order, parents before their children.
 // Time complexity: O(n) where n is the total number of nodes in the YAML structure
 // Space complexity: O(n) for storing all paths + O(d) for recursion stack depth d
 func ExtractValuePaths(valuesYaml string) ([]string, error) {
-	var data map[string]interface{}
-	if err := yaml.Unmarshal([]byte(valuesYaml), &data); err != nil {
+	infos, err := DescribeValuePaths(valuesYaml)
+	if err != nil {
 		return nil, err
 	}
 
 	var paths []string
-	extractPaths("", data, &paths)
+	for _, info := range infos {
+		paths = append(paths, info.Path)
+	}
 	return paths, nil
 }
 
-func extractPaths(prefix string, data interface{}, paths *[]string) {
-	switch v := data.(type) {
-	case map[string]interface{}:
-		for key, value := range v {
-			fullPath := key
+// ValueInfo describes a value path of a chart's values.yaml
+type ValueInfo struct {
+	Path    string      `json:"path"`
+	Type    ValueType   `json:"type"`
+	Default interface{} `json:"default"`
+	// Line is the 1-based line of the value's key (or slice element) in values.yaml
+	Line int `json:"line"`
+	// Description documents the value. It is taken from a helm-docs "# --" comment or a bitnami
+	// readme-generator "## @param" annotation, falling back to the plain comment attached to the value.
+	Description string `json:"description,omitempty"`
+}
+
+// DescribeValuePaths returns the type, default value, line and description of every value path in valuesYaml, in source order
+func DescribeValuePaths(valuesYaml string) ([]ValueInfo, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(valuesYaml), &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	params := make(map[string]string)
+	collectParamDocs(&doc, params, make(map[*yaml.Node]bool))
+
+	var infos []ValueInfo
+	if err := describeNode("", doc.Content[0], params, &infos); err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
+
+// describeNode appends a ValueInfo for every map key and slice element below node
+func describeNode(prefix string, node *yaml.Node, params map[string]string, infos *[]ValueInfo) error {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			fullPath := key.Value
 			if prefix != "" {
-				fullPath = prefix + "." + key
+				fullPath = prefix + "." + key.Value
+			}
+			description := describeValue(fullPath, params, key.HeadComment, key.LineComment+"\n"+value.LineComment)
+			if err := appendValueInfo(fullPath, key.Line, description, value, params, infos); err != nil {
+				return err
 			}
-			*paths = append(*paths, fullPath)
-			extractPaths(fullPath, value, paths)
 		}
-	case []interface{}:
-		for i, item := range v {
-			indexPath := prefix + "[" + string(rune(i+'0')) + "]"
-			*paths = append(*paths, indexPath)
-			extractPaths(indexPath, item, paths)
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			indexPath := prefix + "[" + strconv.Itoa(i) + "]"
+			description := describeValue(indexPath, params, item.HeadComment, item.LineComment)
+			if err := appendValueInfo(indexPath, item.Line, description, item, params, infos); err != nil {
+				return err
+			}
 		}
 	}
+	return nil
+}
+
+func appendValueInfo(path string, line int, description string, value *yaml.Node, params map[string]string, infos *[]ValueInfo) error {
+	var decoded interface{}
+	if err := value.Decode(&decoded); err != nil {
+		return fmt.Errorf("failed to decode value at path %s: %v", path, err)
+	}
+
+	*infos = append(*infos, ValueInfo{
+		Path:        path,
+		Type:        determineValueType(decoded),
+		Default:     decoded,
+		Line:        line,
+		Description: description,
+	})
+	return describeNode(path, value, params, infos)
+}
+
+// describeValue picks the description of a value from its comments.
+// A helm-docs "# --" comment wins over a "## @param" annotation, which wins over the plain comment.
+func describeValue(path string, params map[string]string, headComment, lineComment string) string {
+	if description, ok := helmDocsDescription(headComment + "\n" + lineComment); ok {
+		return description
+	}
+	if description, ok := params[path]; ok {
+		return description
+	}
+
+	// Only the paragraph right above the key belongs to it; earlier ones are section headers or license text
+	paragraphs := commentParagraphs(headComment)
+	var lines []string
+	if len(paragraphs) > 0 {
+		lines = paragraphs[len(paragraphs)-1]
+	}
+	lines = append(lines, commentLines(lineComment)...)
+
+	var words []string
+	for _, line := range lines {
+		// Annotations of documentation generators are not descriptions
+		if !strings.HasPrefix(line, "@") {
+			words = append(words, line)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// helmDocsDescription returns the text of the last "# --" comment and the lines continuing it.
+// A leading "(type)" is dropped, and the description ends at the first "@" annotation such as "# @default --".
+func helmDocsDescription(comment string) (string, bool) {
+	lines := commentLines(comment)
+	start := -1
+	for i, line := range lines {
+		if line == "--" || strings.HasPrefix(line, "-- ") {
+			start = i
+		}
+	}
+	if start < 0 {
+		return "", false
+	}
+
+	first := strings.TrimSpace(strings.TrimPrefix(lines[start], "--"))
+	if strings.HasPrefix(first, "(") {
+		if end := strings.Index(first, ")"); end >= 0 {
+			first = strings.TrimSpace(first[end+1:])
+		}
+	}
+
+	words := []string{first}
+	for _, line := range lines[start+1:] {
+		if strings.HasPrefix(line, "@") {
+			break
+		}
+		words = append(words, line)
+	}
+	return strings.TrimSpace(strings.Join(words, " ")), true
+}
+
+// collectParamDocs gathers the "## @param <path> [modifiers] <description>" annotations found in any comment below node
+func collectParamDocs(node *yaml.Node, params map[string]string, seen map[*yaml.Node]bool) {
+	if node == nil || seen[node] {
+		return
+	}
+	seen[node] = true
+
+	for _, comment := range []string{node.HeadComment, node.LineComment, node.FootComment} {
+		for _, line := range commentLines(comment) {
+			rest, ok := strings.CutPrefix(line, "@param ")
+			if !ok {
+				continue
+			}
+			path, description, _ := strings.Cut(strings.TrimSpace(rest), " ")
+			description = strings.TrimSpace(description)
+			// Modifiers such as [string] or [default: 1] precede the description
+			for strings.HasPrefix(description, "[") {
+				end := strings.Index(description, "]")
+				if end < 0 {
+					break
+				}
+				description = strings.TrimSpace(description[end+1:])
+			}
+			params[path] = description
+		}
+	}
+
+	for _, child := range node.Content {
+		collectParamDocs(child, params, seen)
+	}
+}
+
+// commentParagraphs splits a comment into paragraphs separated by blank lines or lines holding only "#"
+func commentParagraphs(comment string) [][]string {
+	var paragraphs [][]string
+	var current []string
+	for _, line := range strings.Split(comment, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+		if line == "" {
+			if len(current) > 0 {
+				paragraphs = append(paragraphs, current)
+				current = nil
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		paragraphs = append(paragraphs, current)
+	}
+	return paragraphs
+}
+
+// commentLines strips the comment markers from YAML comments and returns their non-empty lines
+func commentLines(comment string) []string {
+	var lines []string
+	for _, paragraph := range commentParagraphs(comment) {
+		lines = append(lines, paragraph...)
+	}
+	return lines
+}
+
+// Summary formats the type and default value shown next to the path, e.g. `string = "nginx"` or `map (2 keys)`
+func (v ValueInfo) Summary() string {
+	switch d := v.Default.(type) {
+	case nil:
+		return v.Type.String() + " = null"
+	case map[string]interface{}:
+		return fmt.Sprintf("%s (%s)", v.Type, countNoun(len(d), "key", "keys"))
+	case []interface{}:
+		return fmt.Sprintf("%s (%s)", v.Type, countNoun(len(d), "item", "items"))
+	case string:
+		return v.Type.String() + " = " + strconv.Quote(d)
+	default:
+		return fmt.Sprintf("%s = %v", v.Type, d)
+	}
+}
+
+// countNoun formats count followed by the singular or plural noun, e.g. "1 item" or "2 items"
+func countNoun(count int, singular, plural string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, singular)
+	}
+	return fmt.Sprintf("%d %s", count, plural)
+}
+
+// String returns the name of the value type as shown to users
+func (t ValueType) String() string {
+	switch t {
+	case ValueTypeString:
+		return "string"
+	case ValueTypeInt:
+		return "int"
+	case ValueTypeBool:
+		return "bool"
+	case ValueTypeSlice:
+		return "slice"
+	case ValueTypeMap:
+		return "map"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText encodes the value type by its name, e.g. in JSON reports
+func (t ValueType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
 }
 
 // GetValueType determines the type of a value at the specified path in the YAML structure
@@ -66,6 +298,7 @@ func GetValueType(valuesYaml, path string) (ValueType, error) {
 	return determineValueType(value), nil
 }
 
+// getValueAtPath returns the value at path, whose "[i]" segments index into lists
 func getValueAtPath(data interface{}, path string) (interface{}, error) {
 	if path == "" {
 		return data, nil
@@ -75,6 +308,18 @@ func getValueAtPath(data interface{}, path string) (interface{}, error) {
 	current := data
 
 	for _, key := range keys {
+		if index, ok := pathIndex(key); ok {
+			list, ok := current.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("cannot access index %s on non-list type", key)
+			}
+			if index >= len(list) {
+				return nil, fmt.Errorf("index %s out of range", key)
+			}
+			current = list[index]
+			continue
+		}
+
 		switch v := current.(type) {
 		case map[string]interface{}:
 			if val, ok := v[key]; ok {
@@ -90,6 +335,7 @@ func getValueAtPath(data interface{}, path string) (interface{}, error) {
 	return current, nil
 }
 
+// splitPath splits a value path such as "service.ports[0].port" into its keys, keeping list indexes as "[0]" segments
 func splitPath(path string) []string {
 	if path == "" {
 		return []string{}
@@ -99,12 +345,21 @@ func splitPath(path string) []string {
 	var current string
 
 	for _, char := range path {
-		if char == '.' {
+		switch char {
+		case '.':
 			if current != "" {
 				parts = append(parts, current)
 				current = ""
 			}
-		} else {
+		case '[':
+			if current != "" {
+				parts = append(parts, current)
+			}
+			current = "["
+		case ']':
+			parts = append(parts, current+"]")
+			current = ""
+		default:
 			current += string(char)
 		}
 	}
@@ -116,6 +371,18 @@ func splitPath(path string) []string {
 	return parts
 }
 
+// pathIndex returns the list index of a "[i]" path segment
+func pathIndex(key string) (int, bool) {
+	if !strings.HasPrefix(key, "[") || !strings.HasSuffix(key, "]") {
+		return 0, false
+	}
+	index, err := strconv.Atoi(key[1 : len(key)-1])
+	if err != nil || index < 0 {
+		return 0, false
+	}
+	return index, true
+}
+
 func determineValueType(value interface{}) ValueType {
 	switch value.(type) {
 	case string:
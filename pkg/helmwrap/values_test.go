@@ -2,7 +2,6 @@ package helmwrap
 
 import (
 	"reflect"
-	"sort"
 	"testing"
 )
 
@@ -23,6 +22,16 @@ key2: value2
 `,
 			want: []string{"key1", "key2"},
 		},
+		{
+			name: "should keep source order",
+			valuesYaml: `
+zeta: 1
+alpha:
+  nested: true
+middle: 2
+`,
+			want: []string{"zeta", "alpha", "alpha.nested", "middle"},
+		},
 		{
 			name: "should extract nested paths",
 			valuesYaml: `
@@ -83,9 +92,7 @@ items:
 				return
 			}
 			if !tt.wantErr {
-				// Sort both slices for comparison since map iteration order is not guaranteed
-				sort.Strings(got)
-				sort.Strings(tt.want)
+				// Paths follow the source order of values.yaml
 				if !reflect.DeepEqual(got, tt.want) {
 					t.Errorf("ExtractValuePaths() = %v, want %v", got, tt.want)
 				}
@@ -213,3 +220,120 @@ app:
 		})
 	}
 }
+
+func TestDescribeValuePaths(t *testing.T) {
+	t.Parallel()
+
+	valuesYaml := `# Default values for app.
+# This is a YAML-formatted file.
+
+## @section Common parameters
+## @param nameOverride [string, nullable] String to partially override the fullname
+## @param image.tag Image tag
+##
+
+# Number of pods
+# to run
+replicaCount: 2
+nameOverride: ""
+image:
+  # -- (string) Image repository
+  # used by every container
+  # @default -- the chart's registry
+  repository: nginx
+  # An unrelated comment
+  # -- Image pull policy
+  pullPolicy: IfNotPresent
+  tag: "1.27" # superseded by the @param annotation
+  digest: "" # -- Pin the image by digest
+ports: [80, 81, 82, 83, 84, 85, 86, 87, 88, 89, 90]
+`
+
+	infos, err := DescribeValuePaths(valuesYaml)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := make(map[string]ValueInfo)
+	for _, info := range infos {
+		got[info.Path] = info
+	}
+
+	tests := []struct {
+		path            string
+		wantType        ValueType
+		wantDefault     interface{}
+		wantLine        int
+		wantDescription string
+	}{
+		{path: "replicaCount", wantType: ValueTypeInt, wantDefault: 2, wantLine: 11, wantDescription: "Number of pods to run"},
+		{path: "nameOverride", wantType: ValueTypeString, wantDefault: "", wantLine: 12, wantDescription: "String to partially override the fullname"},
+		{path: "image", wantType: ValueTypeMap, wantLine: 13},
+		{path: "image.repository", wantType: ValueTypeString, wantDefault: "nginx", wantLine: 17, wantDescription: "Image repository used by every container"},
+		{path: "image.pullPolicy", wantType: ValueTypeString, wantDefault: "IfNotPresent", wantLine: 20, wantDescription: "Image pull policy"},
+		{path: "image.tag", wantType: ValueTypeString, wantDefault: "1.27", wantLine: 21, wantDescription: "Image tag"},
+		{path: "image.digest", wantType: ValueTypeString, wantDefault: "", wantLine: 22, wantDescription: "Pin the image by digest"},
+		{path: "ports", wantType: ValueTypeSlice, wantLine: 23},
+		{path: "ports[10]", wantType: ValueTypeInt, wantDefault: 90, wantLine: 23},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			t.Parallel()
+
+			info, ok := got[tt.path]
+			if !ok {
+				t.Fatalf("path %s not described, got %v", tt.path, infos)
+			}
+			if info.Type != tt.wantType {
+				t.Errorf("expected type %v, got %v", tt.wantType, info.Type)
+			}
+			if tt.wantDefault != nil && info.Default != tt.wantDefault {
+				t.Errorf("expected default %v, got %v", tt.wantDefault, info.Default)
+			}
+			if info.Line != tt.wantLine {
+				t.Errorf("expected line %d, got %d", tt.wantLine, info.Line)
+			}
+			if info.Description != tt.wantDescription {
+				t.Errorf("expected description %q, got %q", tt.wantDescription, info.Description)
+			}
+		})
+	}
+
+	var paths []string
+	for _, info := range infos {
+		paths = append(paths, info.Path)
+	}
+	wantPrefix := []string{"replicaCount", "nameOverride", "image", "image.repository", "image.pullPolicy", "image.tag", "image.digest", "ports", "ports[0]"}
+	if len(paths) != 19 || !reflect.DeepEqual(paths[:len(wantPrefix)], wantPrefix) {
+		t.Errorf("expected 19 paths in source order starting with %v, got %v", wantPrefix, paths)
+	}
+}
+
+func TestValueInfoSummary(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		info ValueInfo
+		want string
+	}{
+		{name: "string", info: ValueInfo{Type: ValueTypeString, Default: "nginx"}, want: `string = "nginx"`},
+		{name: "int", info: ValueInfo{Type: ValueTypeInt, Default: 3}, want: "int = 3"},
+		{name: "null", info: ValueInfo{Type: ValueTypeUnknown}, want: "unknown = null"},
+		{name: "map", info: ValueInfo{Type: ValueTypeMap, Default: map[string]interface{}{"a": 1, "b": 2}}, want: "map (2 keys)"},
+		{name: "map with one key", info: ValueInfo{Type: ValueTypeMap, Default: map[string]interface{}{"a": 1}}, want: "map (1 key)"},
+		{name: "slice", info: ValueInfo{Type: ValueTypeSlice, Default: []interface{}{1, 2}}, want: "slice (2 items)"},
+		{name: "slice with one item", info: ValueInfo{Type: ValueTypeSlice, Default: []interface{}{1}}, want: "slice (1 item)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.info.Summary(); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
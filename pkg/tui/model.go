@@ -0,0 +1,406 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Drumato/helmhound/pkg/helmwrap"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// impactDebounce delays rendering the impact until the highlight stops moving
+const impactDebounce = 150 * time.Millisecond
+
+var (
+	highlightStyle = lipgloss.NewStyle().Reverse(true)
+	dimStyle       = lipgloss.NewStyle().Faint(true)
+	headerStyle    = lipgloss.NewStyle().Bold(true)
+	errorStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+)
+
+// node is a value path in the tree
+type node struct {
+	info        helmwrap.ValueInfo
+	depth       int
+	label       string
+	parent      string
+	hasChildren bool
+}
+
+// impactResult is the cached impact of modifying a value path
+type impactResult struct {
+	text string
+	err  error
+}
+
+type impactMsg struct {
+	path string
+	impactResult
+}
+
+type debounceMsg struct {
+	seq int
+}
+
+// model is the state of the value browser
+type model struct {
+	nodes  []node
+	impact ImpactFunc
+
+	query     string
+	collapsed map[string]bool
+	cursor    int
+	offset    int
+	width     int
+	height    int
+
+	impacts  map[string]impactResult
+	inflight string
+	seq      int
+
+	multi    bool
+	marked   map[string]bool
+	selected []string
+	canceled bool
+}
+
+func newModel(values []helmwrap.ValueInfo, impact ImpactFunc, multi bool) *model {
+	m := &model{
+		impact:    impact,
+		multi:     multi,
+		marked:    make(map[string]bool),
+		collapsed: make(map[string]bool),
+		impacts:   make(map[string]impactResult),
+		width:     100,
+		height:    24,
+	}
+
+	parents := make(map[string]int)
+	for _, info := range values {
+		label, parent := splitLastSegment(info.Path)
+		if i, ok := parents[parent]; ok {
+			m.nodes[i].hasChildren = true
+		}
+		parents[info.Path] = len(m.nodes)
+		m.nodes = append(m.nodes, node{
+			info:   info,
+			depth:  strings.Count(info.Path, ".") + strings.Count(info.Path, "["),
+			label:  label,
+			parent: parent,
+		})
+	}
+
+	return m
+}
+
+// splitLastSegment splits "a.b[0]" into "[0]" and "a.b", and "a.b" into "b" and "a"
+func splitLastSegment(path string) (string, string) {
+	i := strings.LastIndexAny(path, ".[")
+	switch {
+	case i < 0:
+		return path, ""
+	case path[i] == '.':
+		return path[i+1:], path[:i]
+	default:
+		return path[i:], path[:i]
+	}
+}
+
+// visible returns the nodes shown in the list: the matches of the query, or the expanded part of the tree
+func (m *model) visible() []node {
+	var nodes []node
+	if m.query != "" {
+		for _, n := range m.nodes {
+			if fuzzyMatch(n.info.Path, m.query) {
+				nodes = append(nodes, n)
+			}
+		}
+		return nodes
+	}
+
+	hidden := make(map[string]bool)
+	for _, n := range m.nodes {
+		if hidden[n.parent] || m.collapsed[n.parent] {
+			hidden[n.info.Path] = true
+			continue
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// fuzzyMatch reports whether the characters of query appear in order in path, ignoring case
+func fuzzyMatch(path, query string) bool {
+	path = strings.ToLower(path)
+	for _, r := range strings.ToLower(query) {
+		i := strings.IndexRune(path, r)
+		if i < 0 {
+			return false
+		}
+		path = path[i+len(string(r)):]
+	}
+	return true
+}
+
+// current returns the highlighted node
+func (m *model) current() (node, bool) {
+	nodes := m.visible()
+	if m.cursor < 0 || m.cursor >= len(nodes) {
+		return node{}, false
+	}
+	return nodes[m.cursor], true
+}
+
+func (m *model) Init() tea.Cmd {
+	return m.requestImpact()
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.clampCursor()
+		return m, nil
+
+	case impactMsg:
+		m.impacts[msg.path] = msg.impactResult
+		m.inflight = ""
+		return m, m.requestImpact()
+
+	case debounceMsg:
+		if msg.seq != m.seq {
+			return m, nil
+		}
+		return m, m.requestImpact()
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+
+	return m, nil
+}
+
+func (m *model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	previous, _ := m.current()
+
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		m.canceled = true
+		return m, tea.Quit
+	case tea.KeyEsc:
+		if m.query == "" {
+			m.canceled = true
+			return m, tea.Quit
+		}
+		m.query = ""
+		m.cursor = m.indexOf(previous.info.Path)
+	case tea.KeyEnter:
+		// Marked values are returned in tree order; without marks the highlighted value is selected
+		for _, n := range m.nodes {
+			if m.marked[n.info.Path] {
+				m.selected = append(m.selected, n.info.Path)
+			}
+		}
+		if n, ok := m.current(); ok && len(m.selected) == 0 {
+			m.selected = []string{n.info.Path}
+		}
+		if len(m.selected) > 0 {
+			return m, tea.Quit
+		}
+	case tea.KeyTab:
+		if m.multi {
+			if m.marked[previous.info.Path] {
+				delete(m.marked, previous.info.Path)
+			} else {
+				m.marked[previous.info.Path] = true
+			}
+			m.cursor++
+		}
+	case tea.KeyUp, tea.KeyCtrlP:
+		m.cursor--
+	case tea.KeyDown, tea.KeyCtrlN:
+		m.cursor++
+	case tea.KeyPgUp:
+		m.cursor -= m.listHeight()
+	case tea.KeyPgDown:
+		m.cursor += m.listHeight()
+	case tea.KeyHome:
+		m.cursor = 0
+	case tea.KeyEnd:
+		m.cursor = len(m.visible()) - 1
+	case tea.KeyLeft:
+		if m.query == "" {
+			if previous.hasChildren && !m.collapsed[previous.info.Path] {
+				m.collapsed[previous.info.Path] = true
+			} else if previous.parent != "" {
+				m.cursor = m.indexOf(previous.parent)
+			}
+		}
+	case tea.KeyRight:
+		if m.query == "" {
+			delete(m.collapsed, previous.info.Path)
+		}
+	case tea.KeyBackspace:
+		if m.query != "" {
+			m.query = string([]rune(m.query)[:len([]rune(m.query))-1])
+			m.cursor = 0
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.query += string(msg.Runes)
+		m.cursor = 0
+	}
+
+	m.clampCursor()
+
+	if n, ok := m.current(); ok && n.info.Path != previous.info.Path {
+		m.seq++
+		seq := m.seq
+		return m, tea.Tick(impactDebounce, func(time.Time) tea.Msg { return debounceMsg{seq: seq} })
+	}
+	return m, nil
+}
+
+// requestImpact starts rendering the impact of the highlighted path unless it is cached.
+// Renders share the helm client and run one at a time.
+func (m *model) requestImpact() tea.Cmd {
+	n, ok := m.current()
+	if !ok || m.inflight != "" || m.impact == nil {
+		return nil
+	}
+	if _, cached := m.impacts[n.info.Path]; cached {
+		return nil
+	}
+
+	path := n.info.Path
+	m.inflight = path
+	impact := m.impact
+	return func() tea.Msg {
+		text, err := impact(path)
+		return impactMsg{path: path, impactResult: impactResult{text: text, err: err}}
+	}
+}
+
+// indexOf returns the index of path among the visible nodes, or 0 if it is not visible
+func (m *model) indexOf(path string) int {
+	for i, n := range m.visible() {
+		if n.info.Path == path {
+			return i
+		}
+	}
+	return 0
+}
+
+func (m *model) clampCursor() {
+	count := len(m.visible())
+	if m.cursor >= count {
+		m.cursor = count - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+
+	height := m.listHeight()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+height {
+		m.offset = m.cursor - height + 1
+	}
+}
+
+// listHeight is the number of rows available to the value list and the impact pane
+func (m *model) listHeight() int {
+	// search box, separator, separator and help line
+	return max(m.height-4, 1)
+}
+
+func (m *model) View() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Search: %s█\n", m.query)
+	b.WriteString(dimStyle.Render(strings.Repeat("─", m.width)) + "\n")
+
+	leftWidth := max(m.width*2/5, 20)
+	rightWidth := max(m.width-leftWidth-3, 10)
+	height := m.listHeight()
+
+	left := lipgloss.NewStyle().Width(leftWidth).MaxWidth(leftWidth).Height(height).MaxHeight(height).Render(m.viewList(leftWidth, height))
+	right := lipgloss.NewStyle().Width(rightWidth).MaxWidth(rightWidth).Height(height).MaxHeight(height).Render(m.viewImpact())
+	separator := dimStyle.Render(strings.TrimSuffix(strings.Repeat(" │ \n", height), "\n"))
+	b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, left, separator, right) + "\n")
+
+	b.WriteString(dimStyle.Render(strings.Repeat("─", m.width)) + "\n")
+	help := "↑/↓ move  ←/→ collapse/expand  type to search  enter select  esc clear/cancel"
+	if m.multi {
+		help += "  tab mark"
+	}
+	b.WriteString(dimStyle.Render(help))
+
+	return b.String()
+}
+
+func (m *model) viewList(width, height int) string {
+	nodes := m.visible()
+	if len(nodes) == 0 {
+		return dimStyle.Render("no matching values")
+	}
+
+	var lines []string
+	for i := m.offset; i < len(nodes) && i < m.offset+height; i++ {
+		n := nodes[i]
+
+		line := "  "
+		if m.marked[n.info.Path] {
+			line = "● "
+		}
+		if m.query != "" {
+			line += n.info.Path
+		} else {
+			marker := "  "
+			if n.hasChildren {
+				marker = "▾ "
+				if m.collapsed[n.info.Path] {
+					marker = "▸ "
+				}
+			}
+			line += strings.Repeat("  ", n.depth) + marker + n.label
+		}
+		line += "  " + dimStyle.Render(n.info.Summary())
+
+		if i == m.cursor {
+			line = highlightStyle.Render(lipgloss.NewStyle().MaxWidth(width).Render(line))
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (m *model) viewImpact() string {
+	n, ok := m.current()
+	if !ok {
+		return ""
+	}
+
+	header := headerStyle.Render("Impact of "+n.info.Path) + "\n"
+	if n.info.Line > 0 {
+		header += dimStyle.Render(fmt.Sprintf("values.yaml:%d", n.info.Line)) + "\n"
+	}
+	if n.info.Description != "" {
+		header += dimStyle.Render(n.info.Description) + "\n"
+	}
+	header += "\n"
+	result, cached := m.impacts[n.info.Path]
+	switch {
+	case !cached:
+		return header + dimStyle.Render("Rendering...")
+	case result.err != nil:
+		return header + errorStyle.Render(result.err.Error())
+	case strings.TrimSpace(result.text) == "":
+		return header + dimStyle.Render("No differences in the rendered manifests")
+	default:
+		return header + result.text
+	}
+}
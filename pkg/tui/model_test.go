@@ -0,0 +1,196 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Drumato/helmhound/pkg/helmwrap"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func testValues(t *testing.T) []helmwrap.ValueInfo {
+	t.Helper()
+
+	// Listed explicitly so that the tree order does not depend on map iteration
+	return []helmwrap.ValueInfo{
+		{Path: "image", Type: helmwrap.ValueTypeMap, Default: map[string]interface{}{"repository": "nginx", "tag": "1.27"}},
+		{Path: "image.repository", Type: helmwrap.ValueTypeString, Default: "nginx"},
+		{Path: "image.tag", Type: helmwrap.ValueTypeString, Default: "1.27"},
+		{Path: "replicaCount", Type: helmwrap.ValueTypeInt, Default: 1, Line: 4, Description: "Number of pods"},
+		{Path: "ports", Type: helmwrap.ValueTypeSlice, Default: []interface{}{80}},
+		{Path: "ports[0]", Type: helmwrap.ValueTypeInt, Default: 80},
+	}
+}
+
+func visiblePaths(m *model) []string {
+	var paths []string
+	for _, n := range m.visible() {
+		paths = append(paths, n.info.Path)
+	}
+	return paths
+}
+
+func keyRunes(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func TestModelNavigation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		keys         []tea.KeyMsg
+		wantVisible  []string
+		wantCurrent  string
+		multi        bool
+		wantSelected []string
+		wantCanceled bool
+	}{
+		{
+			name:        "tree shows every value",
+			wantVisible: []string{"image", "image.repository", "image.tag", "replicaCount", "ports", "ports[0]"},
+			wantCurrent: "image",
+		},
+		{
+			name:        "left collapses a map",
+			keys:        []tea.KeyMsg{{Type: tea.KeyLeft}},
+			wantVisible: []string{"image", "replicaCount", "ports", "ports[0]"},
+			wantCurrent: "image",
+		},
+		{
+			name:        "right expands a collapsed map",
+			keys:        []tea.KeyMsg{{Type: tea.KeyLeft}, {Type: tea.KeyRight}},
+			wantVisible: []string{"image", "image.repository", "image.tag", "replicaCount", "ports", "ports[0]"},
+			wantCurrent: "image",
+		},
+		{
+			name:        "left on a leaf moves to its parent",
+			keys:        []tea.KeyMsg{{Type: tea.KeyDown}, {Type: tea.KeyDown}, {Type: tea.KeyLeft}},
+			wantVisible: []string{"image", "image.repository", "image.tag", "replicaCount", "ports", "ports[0]"},
+			wantCurrent: "image",
+		},
+		{
+			name:        "search filters paths fuzzily",
+			keys:        []tea.KeyMsg{keyRunes("imgtag")},
+			wantVisible: []string{"image.tag"},
+			wantCurrent: "image.tag",
+		},
+		{
+			name:        "backspace widens the search",
+			keys:        []tea.KeyMsg{keyRunes("rep"), {Type: tea.KeyBackspace}},
+			wantVisible: []string{"image.repository", "replicaCount"},
+			wantCurrent: "image.repository",
+		},
+		{
+			name:        "escape clears the search and keeps the highlight",
+			keys:        []tea.KeyMsg{keyRunes("count"), {Type: tea.KeyEsc}},
+			wantVisible: []string{"image", "image.repository", "image.tag", "replicaCount", "ports", "ports[0]"},
+			wantCurrent: "replicaCount",
+		},
+		{
+			name:         "enter selects the highlighted path",
+			keys:         []tea.KeyMsg{{Type: tea.KeyEnd}, {Type: tea.KeyEnter}},
+			wantVisible:  []string{"image", "image.repository", "image.tag", "replicaCount", "ports", "ports[0]"},
+			wantCurrent:  "ports[0]",
+			wantSelected: []string{"ports[0]"},
+		},
+		{
+			name:         "tab marks several paths in multi mode",
+			keys:         []tea.KeyMsg{{Type: tea.KeyEnd}, {Type: tea.KeyTab}, {Type: tea.KeyHome}, {Type: tea.KeyTab}, {Type: tea.KeyEnter}},
+			multi:        true,
+			wantVisible:  []string{"image", "image.repository", "image.tag", "replicaCount", "ports", "ports[0]"},
+			wantCurrent:  "image.repository",
+			wantSelected: []string{"image", "ports[0]"},
+		},
+		{
+			name:         "tab is ignored without multi mode",
+			keys:         []tea.KeyMsg{{Type: tea.KeyTab}, {Type: tea.KeyEnter}},
+			wantVisible:  []string{"image", "image.repository", "image.tag", "replicaCount", "ports", "ports[0]"},
+			wantCurrent:  "image",
+			wantSelected: []string{"image"},
+		},
+		{
+			name:         "escape without a search cancels",
+			keys:         []tea.KeyMsg{{Type: tea.KeyEsc}},
+			wantVisible:  []string{"image", "image.repository", "image.tag", "replicaCount", "ports", "ports[0]"},
+			wantCurrent:  "image",
+			wantCanceled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := newModel(testValues(t), nil, tt.multi)
+			for _, key := range tt.keys {
+				m.Update(key)
+			}
+
+			if got := visiblePaths(m); strings.Join(got, ",") != strings.Join(tt.wantVisible, ",") {
+				t.Errorf("expected visible %v, got %v", tt.wantVisible, got)
+			}
+			if n, _ := m.current(); n.info.Path != tt.wantCurrent {
+				t.Errorf("expected current %s, got %s", tt.wantCurrent, n.info.Path)
+			}
+			if strings.Join(m.selected, ",") != strings.Join(tt.wantSelected, ",") {
+				t.Errorf("expected selected %q, got %q", tt.wantSelected, m.selected)
+			}
+			if m.canceled != tt.wantCanceled {
+				t.Errorf("expected canceled %v, got %v", tt.wantCanceled, m.canceled)
+			}
+		})
+	}
+}
+
+func TestModelImpact(t *testing.T) {
+	t.Parallel()
+
+	var rendered []string
+	impact := func(path string) (string, error) {
+		rendered = append(rendered, path)
+		if path == "replicaCount" {
+			return "", fmt.Errorf("render failed")
+		}
+		return "Differences found in " + path, nil
+	}
+
+	m := newModel(testValues(t), impact, false)
+	// run executes cmd and every command its messages lead to, like the program loop would
+	run := func(cmd tea.Cmd) {
+		for cmd != nil {
+			_, cmd = m.Update(cmd())
+		}
+	}
+
+	run(m.Init())
+	if !strings.Contains(m.View(), "Differences found in image") {
+		t.Errorf("expected impact of the first value in the view, got:\n%s", m.View())
+	}
+
+	// Moving quickly only renders the value the highlight settles on
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	_, stale := m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	_, settled := m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if !strings.Contains(m.View(), "Rendering...") {
+		t.Errorf("expected pending impact in the view, got:\n%s", m.View())
+	}
+	staleMsg := stale()
+	run(settled)
+	run(func() tea.Msg { return staleMsg })
+
+	if !strings.Contains(m.View(), "render failed") || !strings.Contains(m.View(), "Number of pods") || !strings.Contains(m.View(), "values.yaml:4") {
+		t.Errorf("expected render error, line and description in the view, got:\n%s", m.View())
+	}
+
+	// Returning to a rendered value uses the cache
+	m.Update(tea.KeyMsg{Type: tea.KeyHome})
+	if !strings.Contains(m.View(), "Differences found in image") {
+		t.Errorf("expected cached impact in the view, got:\n%s", m.View())
+	}
+
+	if strings.Join(rendered, ",") != "image,replicaCount" {
+		t.Errorf("expected only the settled values to be rendered, got %v", rendered)
+	}
+}
@@ -0,0 +1,37 @@
+// Package tui implements the interactive value browser used when no value path is given.
+package tui
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Drumato/helmhound/pkg/helmwrap"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ImpactFunc renders a human readable summary of the differences caused by modifying the value at path
+type ImpactFunc func(path string) (string, error)
+
+// Select shows the values as a tree with a search box and a live impact pane and returns the selected paths.
+// With multi, several values can be marked with Tab. The browser is drawn on stderr and reads keys
+// from the terminal, so stdout can still be redirected.
+func Select(values []helmwrap.ValueInfo, impact ImpactFunc, multi bool) ([]string, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no values to select from")
+	}
+
+	program := tea.NewProgram(newModel(values, impact, multi), tea.WithAltScreen(), tea.WithOutput(os.Stderr), tea.WithInputTTY())
+	final, err := program.Run()
+	if err != nil {
+		return nil, fmt.Errorf("value browser failed: %v", err)
+	}
+
+	m := final.(*model)
+	if m.canceled {
+		return nil, fmt.Errorf("selection canceled")
+	}
+	if len(m.selected) == 0 {
+		return nil, fmt.Errorf("no value selected")
+	}
+	return m.selected, nil
+}
@@ -2,10 +2,13 @@ package yamldiff
 
 import (
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 )
 
-// CompareYAML compares two YAML maps and returns a slice of paths where differences are found
+// CompareYAML compares two YAML maps and returns a slice of paths where differences are found.
+// Map keys are visited in sorted order, so the paths are ordered by path.
 func CompareYAML(left, right map[string]interface{}) []string {
 	var diffs []string
 	compareValues("", left, right, &diffs)
@@ -49,24 +52,32 @@ func compareValues(path string, left, right interface{}, diffs *[]string) {
 
 // compareMap compares two maps and records differences
 func compareMap(basePath string, left, right map[string]interface{}, diffs *[]string) {
-	// Check all keys in left map
-	for key, leftValue := range left {
+	for _, key := range unionKeys(left, right) {
 		newPath := buildPath(basePath, key)
-		if rightValue, exists := right[key]; exists {
+		leftValue, inLeft := left[key]
+		rightValue, inRight := right[key]
+		if inLeft && inRight {
 			compareValues(newPath, leftValue, rightValue, diffs)
 		} else {
-			// Key exists in left but not in right
+			// Key exists in only one of the maps
 			*diffs = append(*diffs, newPath)
 		}
 	}
+}
 
-	// Check for keys that exist only in right map
+// unionKeys returns the keys of both maps in sorted order
+func unionKeys(left, right map[string]interface{}) []string {
+	keys := make([]string, 0, len(left)+len(right))
+	for key := range left {
+		keys = append(keys, key)
+	}
 	for key := range right {
 		if _, exists := left[key]; !exists {
-			newPath := buildPath(basePath, key)
-			*diffs = append(*diffs, newPath)
+			keys = append(keys, key)
 		}
 	}
+	sort.Strings(keys)
+	return keys
 }
 
 // compareSlice compares two slices and records differences
@@ -111,8 +122,25 @@ func buildArrayPath(basePath string, index int) string {
 
 // FindDifferencesWithValues compares two YAML maps and returns differences with their values
 func FindDifferencesWithValues(left, right map[string]interface{}) map[string]DiffValue {
+	return FindDifferencesWithOptions(left, right, CompareOptions{})
+}
+
+// CompareOptions configures how FindDifferencesWithOptions compares values
+type CompareOptions struct {
+	// Semantic treats values with the same meaning as equal: resource quantities such as "1000m" and "1",
+	// durations such as "60s" and "1m", and numbers such as 1 and 1.0. Strings holding JSON or YAML documents
+	// are parsed and compared structurally, so their differences are reported at paths below the string.
+	Semantic bool
+	// Payloads compares the entries of ConfigMap and Secret data as the documents they hold. Secret data is decoded
+	// from base64; YAML, JSON and INI files are compared key by key and other multi-line text line by line.
+	// The decoded Secret values are reported as they are, so redact them with a Redactor before printing them.
+	Payloads bool
+}
+
+// FindDifferencesWithOptions compares two YAML maps like FindDifferencesWithValues, configured by opts
+func FindDifferencesWithOptions(left, right map[string]interface{}, opts CompareOptions) map[string]DiffValue {
 	diffs := make(map[string]DiffValue)
-	findDifferencesWithValues("", left, right, diffs)
+	findDifferencesWithValues("", "", left, right, opts, diffs)
 	return diffs
 }
 
@@ -121,6 +149,9 @@ type DiffValue struct {
 	Left  interface{} `json:"left"`
 	Right interface{} `json:"right"`
 	Type  DiffType    `json:"type"`
+	// Pointer is the RFC 6901 JSON Pointer of the difference, e.g. "/spec/template/spec/containers/0/image".
+	// Unlike the path, it stays unambiguous for keys containing dots.
+	Pointer string `json:"pointer"`
 }
 
 // DiffType represents the type of difference
@@ -133,68 +164,81 @@ const (
 )
 
 // findDifferencesWithValues recursively finds differences and stores them with values
-func findDifferencesWithValues(path string, left, right interface{}, diffs map[string]DiffValue) {
+func findDifferencesWithValues(path, pointer string, left, right interface{}, opts CompareOptions, diffs map[string]DiffValue) {
 	// Handle nil cases
 	if left == nil && right == nil {
 		return
 	}
 	if left == nil {
-		diffs[path] = DiffValue{Left: nil, Right: right, Type: DiffTypeAdded}
+		diffs[path] = DiffValue{Left: nil, Right: right, Type: DiffTypeAdded, Pointer: pointer}
 		return
 	}
 	if right == nil {
-		diffs[path] = DiffValue{Left: left, Right: nil, Type: DiffTypeRemoved}
+		diffs[path] = DiffValue{Left: left, Right: nil, Type: DiffTypeRemoved, Pointer: pointer}
+		return
+	}
+
+	if opts.Payloads && findPayloadDifferences(path, pointer, left, right, opts, diffs) {
 		return
 	}
+	if opts.Semantic {
+		if semanticallyEqual(pointer, left, right) {
+			return
+		}
+		if leftDoc, rightDoc, ok := embeddedDocuments(left, right); ok {
+			left, right = leftDoc, rightDoc
+		}
+	}
 
 	leftType := reflect.TypeOf(left)
 	rightType := reflect.TypeOf(right)
 
 	// If types are different, record as modified
 	if leftType != rightType {
-		diffs[path] = DiffValue{Left: left, Right: right, Type: DiffTypeModified}
+		diffs[path] = DiffValue{Left: left, Right: right, Type: DiffTypeModified, Pointer: pointer}
 		return
 	}
 
 	switch leftVal := left.(type) {
 	case map[string]interface{}:
 		rightVal := right.(map[string]interface{})
-		findMapDifferencesWithValues(path, leftVal, rightVal, diffs)
+		findMapDifferencesWithValues(path, pointer, leftVal, rightVal, opts, diffs)
 	case []interface{}:
 		rightVal := right.([]interface{})
-		findSliceDifferencesWithValues(path, leftVal, rightVal, diffs)
+		findSliceDifferencesWithValues(path, pointer, leftVal, rightVal, opts, diffs)
 	default:
 		// Compare primitive values
 		if !reflect.DeepEqual(left, right) {
-			diffs[path] = DiffValue{Left: left, Right: right, Type: DiffTypeModified}
+			diffs[path] = DiffValue{Left: left, Right: right, Type: DiffTypeModified, Pointer: pointer}
 		}
 	}
 }
 
 // findMapDifferencesWithValues finds differences in maps with values
-func findMapDifferencesWithValues(basePath string, left, right map[string]interface{}, diffs map[string]DiffValue) {
-	// Check all keys in left map
-	for key, leftValue := range left {
+func findMapDifferencesWithValues(basePath, basePointer string, left, right map[string]interface{}, opts CompareOptions, diffs map[string]DiffValue) {
+	for _, key := range unionKeys(left, right) {
 		newPath := buildPath(basePath, key)
-		if rightValue, exists := right[key]; exists {
-			findDifferencesWithValues(newPath, leftValue, rightValue, diffs)
-		} else {
+		newPointer := basePointer + "/" + escapePointerToken(key)
+		leftValue, inLeft := left[key]
+		rightValue, inRight := right[key]
+		switch {
+		case inLeft && inRight && (leftValue == nil) != (rightValue == nil):
+			// A key set to null is still present, so it is modified rather than added or removed
+			diffs[newPath] = DiffValue{Left: leftValue, Right: rightValue, Type: DiffTypeModified, Pointer: newPointer}
+		case inLeft && inRight:
+			findDifferencesWithValues(newPath, newPointer, leftValue, rightValue, opts, diffs)
+		case inLeft:
 			// Key exists in left but not in right
-			diffs[newPath] = DiffValue{Left: leftValue, Right: nil, Type: DiffTypeRemoved}
-		}
-	}
-
-	// Check for keys that exist only in right map
-	for key, rightValue := range right {
-		if _, exists := left[key]; !exists {
-			newPath := buildPath(basePath, key)
-			diffs[newPath] = DiffValue{Left: nil, Right: rightValue, Type: DiffTypeAdded}
+			diffs[newPath] = DiffValue{Left: leftValue, Right: nil, Type: DiffTypeRemoved, Pointer: newPointer}
+		default:
+			// Key exists in right but not in left
+			diffs[newPath] = DiffValue{Left: nil, Right: rightValue, Type: DiffTypeAdded, Pointer: newPointer}
 		}
 	}
 }
 
 // findSliceDifferencesWithValues finds differences in slices with values
-func findSliceDifferencesWithValues(basePath string, left, right []interface{}, diffs map[string]DiffValue) {
+func findSliceDifferencesWithValues(basePath, basePointer string, left, right []interface{}, opts CompareOptions, diffs map[string]DiffValue) {
 	maxLen := len(left)
 	if len(right) > maxLen {
 		maxLen = len(right)
@@ -202,20 +246,29 @@ func findSliceDifferencesWithValues(basePath string, left, right []interface{},
 
 	for i := 0; i < maxLen; i++ {
 		newPath := buildArrayPath(basePath, i)
+		newPointer := basePointer + "/" + strconv.Itoa(i)
 
 		if i >= len(left) {
 			// Element exists in right but not in left
-			diffs[newPath] = DiffValue{Left: nil, Right: right[i], Type: DiffTypeAdded}
+			diffs[newPath] = DiffValue{Left: nil, Right: right[i], Type: DiffTypeAdded, Pointer: newPointer}
 		} else if i >= len(right) {
 			// Element exists in left but not in right
-			diffs[newPath] = DiffValue{Left: left[i], Right: nil, Type: DiffTypeRemoved}
+			diffs[newPath] = DiffValue{Left: left[i], Right: nil, Type: DiffTypeRemoved, Pointer: newPointer}
+		} else if (left[i] == nil) != (right[i] == nil) {
+			// A null element is still present, so it is modified rather than added or removed
+			diffs[newPath] = DiffValue{Left: left[i], Right: right[i], Type: DiffTypeModified, Pointer: newPointer}
 		} else {
 			// Compare elements at the same index
-			findDifferencesWithValues(newPath, left[i], right[i], diffs)
+			findDifferencesWithValues(newPath, newPointer, left[i], right[i], opts, diffs)
 		}
 	}
 }
 
+// escapePointerToken escapes a map key for use as a JSON Pointer reference token
+func escapePointerToken(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
+
 // GroupedDifferences represents differences grouped by manifest
 type GroupedDifferences map[string][]string
 
@@ -228,6 +281,39 @@ type GroupedDifferenceItem struct {
 // GroupedDifferencesDetailed represents differences grouped by manifest with detailed information
 type GroupedDifferencesDetailed map[string][]GroupedDifferenceItem
 
+// ManifestKeys returns the manifest keys ordered by kind and then by name
+func (g GroupedDifferences) ManifestKeys() []string {
+	keys := make([]string, 0, len(g))
+	for key := range g {
+		keys = append(keys, key)
+	}
+	SortManifestKeys(keys)
+	return keys
+}
+
+// ManifestKeys returns the manifest keys ordered by kind and then by name
+func (g GroupedDifferencesDetailed) ManifestKeys() []string {
+	keys := make([]string, 0, len(g))
+	for key := range g {
+		keys = append(keys, key)
+	}
+	SortManifestKeys(keys)
+	return keys
+}
+
+// SortManifestKeys sorts manifest keys of the form "Kind_name" by kind and then by name,
+// so that e.g. every Service comes before the first ServiceAccount
+func SortManifestKeys(keys []string) {
+	sort.Slice(keys, func(i, j int) bool {
+		kindI, nameI, _ := strings.Cut(keys[i], "_")
+		kindJ, nameJ, _ := strings.Cut(keys[j], "_")
+		if kindI != kindJ {
+			return kindI < kindJ
+		}
+		return nameI < nameJ
+	})
+}
+
 // CompareYAMLGrouped compares two YAML maps and returns differences grouped by manifest
 func CompareYAMLGrouped(left, right map[string]interface{}) GroupedDifferences {
 	var diffs []string
@@ -261,10 +347,54 @@ func CompareYAMLGroupedDetailed(left, right map[string]interface{}) GroupedDiffe
 	return grouped
 }
 
+// GroupDifferencesDetailed groups differences found by FindDifferencesWithValues by manifest with detailed information,
+// e.g. after dropping ignored ones. The items of each manifest are ordered by their JSON Pointer.
+func GroupDifferencesDetailed(left, right map[string]interface{}, diffs map[string]DiffValue) GroupedDifferencesDetailed {
+	grouped := make(GroupedDifferencesDetailed)
+	for _, path := range SortedPaths(diffs) {
+		manifestKey := pointerManifestKey(diffs[path].Pointer, path)
+		grouped[manifestKey] = append(grouped[manifestKey], GroupedDifferenceItem{
+			Path:        path,
+			DisplayText: createUserFriendlyDisplayText(path, manifestKey, left, right),
+		})
+	}
+	return grouped
+}
+
+// SortedPaths returns the paths of the differences ordered by their JSON Pointer, comparing list indices numerically
+// so that "items[2]" comes before "items[10]"
+func SortedPaths(diffs map[string]DiffValue) []string {
+	paths := make([]string, 0, len(diffs))
+	for path := range diffs {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return comparePointers(diffs[paths[i]].Pointer, diffs[paths[j]].Pointer) < 0
+	})
+	return paths
+}
+
+// pointerManifestKey returns the manifest key of a difference from its JSON Pointer, which unlike the path
+// stays unambiguous for names containing dots
+func pointerManifestKey(pointer, path string) string {
+	if tokens := pointerTokens(pointer); len(tokens) > 0 {
+		return tokens[0]
+	}
+	return extractManifestKey(path)
+}
+
 // createUserFriendlyDisplayText creates a user-friendly display text for a difference
 func createUserFriendlyDisplayText(path, manifestKey string, left, right map[string]interface{}) string {
 	// If the path is exactly the manifest key, it means the entire manifest was affected
 	if path == manifestKey {
+		_, inLeft := left[manifestKey]
+		_, inRight := right[manifestKey]
+		switch {
+		case !inLeft:
+			return "(entire manifest added)"
+		case !inRight:
+			return "(entire manifest removed)"
+		}
 		return "(affects entire manifest)"
 	}
 
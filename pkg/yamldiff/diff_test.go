@@ -233,8 +233,8 @@ func TestFindDifferencesWithValues(t *testing.T) {
 				"key2": 43,
 			},
 			expected: map[string]DiffValue{
-				"key1": {Left: "old", Right: "new", Type: DiffTypeModified},
-				"key2": {Left: 42, Right: 43, Type: DiffTypeModified},
+				"key1": {Left: "old", Right: "new", Type: DiffTypeModified, Pointer: "/key1"},
+				"key2": {Left: 42, Right: 43, Type: DiffTypeModified, Pointer: "/key2"},
 			},
 		},
 		{
@@ -248,8 +248,8 @@ func TestFindDifferencesWithValues(t *testing.T) {
 				"key3": "value3",
 			},
 			expected: map[string]DiffValue{
-				"key2": {Left: "value2", Right: nil, Type: DiffTypeRemoved},
-				"key3": {Left: nil, Right: "value3", Type: DiffTypeAdded},
+				"key2": {Left: "value2", Right: nil, Type: DiffTypeRemoved, Pointer: "/key2"},
+				"key3": {Left: nil, Right: "value3", Type: DiffTypeAdded, Pointer: "/key3"},
 			},
 		},
 		{
@@ -269,7 +269,7 @@ func TestFindDifferencesWithValues(t *testing.T) {
 				},
 			},
 			expected: map[string]DiffValue{
-				"spec.list[0].fieldA": {Left: "valueA1", Right: "valueA1-modified", Type: DiffTypeModified},
+				"spec.list[0].fieldA": {Left: "valueA1", Right: "valueA1-modified", Type: DiffTypeModified, Pointer: "/spec/list/0/fieldA"},
 			},
 		},
 		{
@@ -281,7 +281,40 @@ func TestFindDifferencesWithValues(t *testing.T) {
 				"items": []interface{}{"a", "b", "c"},
 			},
 			expected: map[string]DiffValue{
-				"items[2]": {Left: nil, Right: "c", Type: DiffTypeAdded},
+				"items[2]": {Left: nil, Right: "c", Type: DiffTypeAdded, Pointer: "/items/2"},
+			},
+		},
+		{
+			name: "keys with dots and slashes",
+			left: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{"helm.sh/hook": "pre-install", "a~b": "1"},
+				},
+			},
+			right: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{"helm.sh/hook": "post-install", "a~b": "2"},
+				},
+			},
+			expected: map[string]DiffValue{
+				"metadata.annotations.a~b":          {Left: "1", Right: "2", Type: DiffTypeModified, Pointer: "/metadata/annotations/a~0b"},
+				"metadata.annotations.helm.sh/hook": {Left: "pre-install", Right: "post-install", Type: DiffTypeModified, Pointer: "/metadata/annotations/helm.sh~1hook"},
+			},
+		},
+		{
+			name: "values set to null",
+			left: map[string]interface{}{
+				"annotations": nil,
+				"items":       []interface{}{"a", nil},
+			},
+			right: map[string]interface{}{
+				"annotations": map[string]interface{}{"a": "b"},
+				"items":       []interface{}{nil, "b"},
+			},
+			expected: map[string]DiffValue{
+				"annotations": {Left: nil, Right: map[string]interface{}{"a": "b"}, Type: DiffTypeModified, Pointer: "/annotations"},
+				"items[0]":    {Left: "a", Right: nil, Type: DiffTypeModified, Pointer: "/items/0"},
+				"items[1]":    {Left: nil, Right: "b", Type: DiffTypeModified, Pointer: "/items/1"},
 			},
 		},
 	}
@@ -580,7 +613,7 @@ func TestCompareYAMLGroupedDetailed(t *testing.T) {
 				"Secret/alertmanager": {
 					{
 						Path:        "Secret/alertmanager",
-						DisplayText: "(affects entire manifest)",
+						DisplayText: "(entire manifest removed)",
 					},
 				},
 			},
@@ -599,7 +632,7 @@ func TestCompareYAMLGroupedDetailed(t *testing.T) {
 				"Secret/alertmanager": {
 					{
 						Path:        "Secret/alertmanager",
-						DisplayText: "(affects entire manifest)",
+						DisplayText: "(entire manifest added)",
 					},
 				},
 			},
@@ -669,7 +702,7 @@ func TestCompareYAMLGroupedDetailed(t *testing.T) {
 				"Secret/alertmanager": {
 					{
 						Path:        "Secret/alertmanager",
-						DisplayText: "(affects entire manifest)",
+						DisplayText: "(entire manifest removed)",
 					},
 				},
 				"ConfigMap/config": {
@@ -681,7 +714,7 @@ func TestCompareYAMLGroupedDetailed(t *testing.T) {
 				"Deployment/app": {
 					{
 						Path:        "Deployment/app",
-						DisplayText: "(affects entire manifest)",
+						DisplayText: "(entire manifest added)",
 					},
 				},
 			},
@@ -738,7 +771,7 @@ func TestCreateUserFriendlyDisplayText(t *testing.T) {
 				},
 			},
 			right:    map[string]interface{}{},
-			expected: "(affects entire manifest)",
+			expected: "(entire manifest removed)",
 		},
 		{
 			name:        "entire manifest added",
@@ -752,6 +785,18 @@ func TestCreateUserFriendlyDisplayText(t *testing.T) {
 					},
 				},
 			},
+			expected: "(entire manifest added)",
+		},
+		{
+			name:        "entire manifest replaced",
+			path:        "document_2",
+			manifestKey: "document_2",
+			left: map[string]interface{}{
+				"document_2": map[string]interface{}{"data": "a"},
+			},
+			right: map[string]interface{}{
+				"document_2": "not a mapping",
+			},
 			expected: "(affects entire manifest)",
 		},
 		{
@@ -788,3 +833,77 @@ func TestCreateUserFriendlyDisplayText(t *testing.T) {
 		})
 	}
 }
+
+func TestCompareYAMLOrder(t *testing.T) {
+	t.Parallel()
+
+	left := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": 1,
+			"selector": "app",
+			"template": map[string]interface{}{"image": "nginx", "args": []interface{}{"a", "b"}},
+		},
+		"metadata": map[string]interface{}{"name": "web", "labels": map[string]interface{}{"tier": "front"}},
+	}
+	right := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": 2,
+			"template": map[string]interface{}{"image": "httpd", "args": []interface{}{"a", "c", "d"}},
+			"strategy": "Recreate",
+		},
+		"metadata": map[string]interface{}{"name": "web2", "labels": map[string]interface{}{"tier": "back"}},
+	}
+
+	expected := []string{
+		"metadata.labels.tier",
+		"metadata.name",
+		"spec.replicas",
+		"spec.selector",
+		"spec.strategy",
+		"spec.template.args[1]",
+		"spec.template.args[2]",
+		"spec.template.image",
+	}
+
+	// Map iteration order is randomized, so repeated runs would catch nondeterminism
+	for i := 0; i < 20; i++ {
+		if got := CompareYAML(left, right); !reflect.DeepEqual(got, expected) {
+			t.Fatalf("expected %v, got %v", expected, got)
+		}
+	}
+}
+
+func TestSortedPaths(t *testing.T) {
+	t.Parallel()
+
+	left := map[string]interface{}{"list": []interface{}{}, "name": "web"}
+	right := map[string]interface{}{"list": make([]interface{}, 11), "name": "api"}
+	for i := range right["list"].([]interface{}) {
+		right["list"].([]interface{})[i] = i
+	}
+
+	expected := []string{"list[0]", "list[1]", "list[2]", "list[3]", "list[4]", "list[5]", "list[6]", "list[7]", "list[8]", "list[9]", "list[10]", "name"}
+	if got := SortedPaths(FindDifferencesWithValues(left, right)); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestManifestKeys(t *testing.T) {
+	t.Parallel()
+
+	grouped := GroupedDifferencesDetailed{
+		"ServiceAccount_web": nil,
+		"Service_web":        nil,
+		"Deployment_web":     nil,
+		"Service_api":        nil,
+		"ConfigMap_web":      nil,
+		"document_3":         nil,
+	}
+	expected := []string{"ConfigMap_web", "Deployment_web", "Service_api", "Service_web", "ServiceAccount_web", "document_3"}
+
+	for i := 0; i < 20; i++ {
+		if got := grouped.ManifestKeys(); !reflect.DeepEqual(got, expected) {
+			t.Fatalf("expected %v, got %v", expected, got)
+		}
+	}
+}
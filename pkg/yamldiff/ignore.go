@@ -0,0 +1,254 @@
+package yamldiff
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IgnoreRules suppresses differences at paths matching any of their patterns.
+// A pattern is either a glob matched against the difference path, e.g. "metadata.annotations.checksum/*",
+// or a JSONPath starting with "$", e.g. "$..caBundle". A pattern also matches everything below the paths it matches.
+type IgnoreRules struct {
+	globs     []*regexp.Regexp
+	jsonPaths [][]jsonPathSegment
+}
+
+// IgnoreFile is the format of an ignore rules file
+type IgnoreFile struct {
+	// Ignore lists the patterns of the ignored paths
+	Ignore []string `yaml:"ignore"`
+}
+
+// ReadIgnoreFile reads the patterns of an ignore rules file
+func ReadIgnoreFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file IgnoreFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return file.Ignore, nil
+}
+
+// NewIgnoreRules compiles the patterns into ignore rules
+func NewIgnoreRules(patterns []string) (*IgnoreRules, error) {
+	rules := &IgnoreRules{}
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		if strings.HasPrefix(pattern, "$") {
+			segments, err := parseJSONPath(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSONPath %q: %v", pattern, err)
+			}
+			rules.jsonPaths = append(rules.jsonPaths, segments)
+			continue
+		}
+		rules.globs = append(rules.globs, compileGlob(pattern))
+	}
+	return rules, nil
+}
+
+// Empty reports whether the rules ignore nothing
+func (r *IgnoreRules) Empty() bool {
+	return r == nil || len(r.globs) == 0 && len(r.jsonPaths) == 0
+}
+
+// Filter returns the differences that no rule ignores, together with the number of ignored differences.
+// The differences must come from FindDifferencesWithValues over manifests keyed by "Kind_name".
+func (r *IgnoreRules) Filter(diffs map[string]DiffValue) (map[string]DiffValue, int) {
+	if r.Empty() {
+		return diffs, 0
+	}
+
+	kept := make(map[string]DiffValue, len(diffs))
+	for path, diff := range diffs {
+		if r.Match(path, diff.Pointer) {
+			continue
+		}
+		kept[path] = diff
+	}
+	return kept, len(diffs) - len(kept)
+}
+
+// IgnoredValue replaces the list elements the rules ignore in manifests returned by Strip
+const IgnoredValue = "(ignored)"
+
+// Strip returns a copy of the manifest stored under key without the fields the rules ignore, so that text diffs
+// and patches built from it leave them out as well. Ignored list elements are replaced by IgnoredValue instead of
+// being removed, so that the indexes of the following elements, and the patch pointers built from them, still refer
+// to the manifest. Ignored paths inside ConfigMap and Secret payloads or embedded documents are kept, since they are
+// not fields of the manifest.
+func (r *IgnoreRules) Strip(key string, manifest interface{}) interface{} {
+	if r.Empty() {
+		return manifest
+	}
+	return r.strip(key, "/"+escapePointerToken(key), manifest)
+}
+
+// strip removes the ignored children of the value at path and pointer
+func (r *IgnoreRules) strip(path, pointer string, v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		stripped := make(map[string]interface{}, len(t))
+		for key, value := range t {
+			childPath, childPointer := buildPath(path, key), pointer+"/"+escapePointerToken(key)
+			if r.Match(childPath, childPointer) {
+				continue
+			}
+			stripped[key] = r.strip(childPath, childPointer, value)
+		}
+		return stripped
+	case []interface{}:
+		stripped := make([]interface{}, len(t))
+		for i, value := range t {
+			childPath, childPointer := buildArrayPath(path, i), pointer+"/"+strconv.Itoa(i)
+			if r.Match(childPath, childPointer) {
+				stripped[i] = IgnoredValue
+				continue
+			}
+			stripped[i] = r.strip(childPath, childPointer, value)
+		}
+		return stripped
+	}
+	return v
+}
+
+// Match reports whether a rule ignores the difference at path, whose JSON Pointer is pointer.
+// Globs are matched against the whole path as well as the path inside the manifest, so that
+// "metadata.annotations.checksum/*" applies to every manifest and "Secret_*.data" to Secrets only.
+// JSONPaths are evaluated inside the manifest.
+func (r *IgnoreRules) Match(path, pointer string) bool {
+	if r == nil {
+		return false
+	}
+
+	innerPath := strings.TrimPrefix(path, pointerManifestKey(pointer, path)+".")
+	for _, glob := range r.globs {
+		if glob.MatchString(path) || (innerPath != path && glob.MatchString(innerPath)) {
+			return true
+		}
+	}
+
+	tokens := pointerTokens(pointer)
+	if len(tokens) > 0 {
+		tokens = tokens[1:]
+	}
+	for _, segments := range r.jsonPaths {
+		if matchJSONPath(segments, tokens) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileGlob translates a glob into a regular expression matching the path and everything below it.
+// "*" and "?" match within a path element, "**" matches across elements.
+func compileGlob(glob string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			b.WriteString(`[^.\[]*`)
+		case glob[i] == '?':
+			b.WriteString(`[^.\[]`)
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	// Lines of text payloads are addressed as "path:N"
+	b.WriteString(`(?:$|[.\[:])`)
+	return regexp.MustCompile(b.String())
+}
+
+// jsonPathSegment is a step of a JSONPath: a child or, if descendant is set, any descendant matching name.
+// An empty name matches any element.
+type jsonPathSegment struct {
+	name       string
+	descendant bool
+}
+
+// parseJSONPath parses the supported JSONPath subset: "$", ".name", "..name", ".*", "[*]", "[0]" and "['name']"
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	rest := strings.TrimPrefix(path, "$")
+	var segments []jsonPathSegment
+	for rest != "" {
+		descendant := false
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			descendant = true
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+		}
+
+		var name string
+		switch {
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated bracket")
+			}
+			selector := rest[1:end]
+			rest = rest[end+1:]
+			switch {
+			case selector == "*":
+			case len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0]:
+				name = selector[1 : len(selector)-1]
+			default:
+				if _, err := strconv.Atoi(selector); err != nil {
+					return nil, fmt.Errorf("unsupported selector [%s]", selector)
+				}
+				name = selector
+			}
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name, rest = rest[:end], rest[end:]
+			if name == "" {
+				return nil, fmt.Errorf("empty name")
+			}
+			if name == "*" {
+				name = ""
+			}
+		}
+		segments = append(segments, jsonPathSegment{name: name, descendant: descendant})
+	}
+	return segments, nil
+}
+
+// matchJSONPath reports whether the segments match the pointer tokens or a prefix of them
+func matchJSONPath(segments []jsonPathSegment, tokens []string) bool {
+	if len(segments) == 0 {
+		return true
+	}
+	if len(tokens) == 0 {
+		return false
+	}
+
+	segment := segments[0]
+	if segment.name == "" || segment.name == tokens[0] {
+		if matchJSONPath(segments[1:], tokens[1:]) {
+			return true
+		}
+	}
+	// A descendant segment may also match deeper elements
+	return segment.descendant && matchJSONPath(segments, tokens[1:])
+}
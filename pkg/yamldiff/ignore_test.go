@@ -0,0 +1,235 @@
+package yamldiff
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreRulesMatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		pointer  string
+		expected bool
+	}{
+		{
+			name:     "glob inside every manifest",
+			patterns: []string{"spec.template.metadata.annotations.checksum/*"},
+			path:     "Deployment_web.spec.template.metadata.annotations.checksum/config",
+			pointer:  "/Deployment_web/spec/template/metadata/annotations/checksum~1config",
+			expected: true,
+		},
+		{
+			name:     "glob with manifest key",
+			patterns: []string{"Secret_*.data"},
+			path:     "Secret_web-tls.data.tls.crt",
+			pointer:  "/Secret_web-tls/data/tls.crt",
+			expected: true,
+		},
+		{
+			name:     "glob does not match other kinds",
+			patterns: []string{"Secret_*.data"},
+			path:     "ConfigMap_web.data.tls.crt",
+			pointer:  "/ConfigMap_web/data/tls.crt",
+			expected: false,
+		},
+		{
+			name:     "single star stays within an element",
+			patterns: []string{"metadata.*"},
+			path:     "Deployment_web.metadata.labels.app",
+			pointer:  "/Deployment_web/metadata/labels/app",
+			expected: true,
+		},
+		{
+			name:     "glob must match whole elements",
+			patterns: []string{"metadata.label"},
+			path:     "Deployment_web.metadata.labels.app",
+			pointer:  "/Deployment_web/metadata/labels/app",
+			expected: false,
+		},
+		{
+			name:     "double star crosses elements",
+			patterns: []string{"**.caBundle"},
+			path:     "MutatingWebhookConfiguration_web.webhooks[0].clientConfig.caBundle",
+			pointer:  "/MutatingWebhookConfiguration_web/webhooks/0/clientConfig/caBundle",
+			expected: true,
+		},
+		{
+			name:     "glob matching list elements",
+			patterns: []string{"spec.template.spec.containers[*].env"},
+			path:     "Deployment_web.spec.template.spec.containers[1].env[0].value",
+			pointer:  "/Deployment_web/spec/template/spec/containers/1/env/0/value",
+			expected: true,
+		},
+		{
+			name:     "glob matching the whole manifest",
+			patterns: []string{"Job_*-migrate-*"},
+			path:     "Job_web-migrate-x7k2p",
+			pointer:  "/Job_web-migrate-x7k2p",
+			expected: true,
+		},
+		{
+			name:     "JSONPath descendant",
+			patterns: []string{"$..caBundle"},
+			path:     "MutatingWebhookConfiguration_web.webhooks[0].clientConfig.caBundle",
+			pointer:  "/MutatingWebhookConfiguration_web/webhooks/0/clientConfig/caBundle",
+			expected: true,
+		},
+		{
+			name:     "JSONPath with bracket key",
+			patterns: []string{"$.metadata.annotations['helm.sh/chart']"},
+			path:     "Service_web.metadata.annotations.helm.sh/chart",
+			pointer:  "/Service_web/metadata/annotations/helm.sh~1chart",
+			expected: true,
+		},
+		{
+			name:     "JSONPath wildcard index",
+			patterns: []string{"$.spec.template.spec.containers[*].image"},
+			path:     "Deployment_web.spec.template.spec.containers[0].image",
+			pointer:  "/Deployment_web/spec/template/spec/containers/0/image",
+			expected: true,
+		},
+		{
+			name:     "JSONPath matches below the path",
+			patterns: []string{"$.data"},
+			path:     "Secret_web.data.password",
+			pointer:  "/Secret_web/data/password",
+			expected: true,
+		},
+		{
+			name:     "JSONPath is evaluated inside the manifest",
+			patterns: []string{"$.spec"},
+			path:     "Deployment_web.metadata.name",
+			pointer:  "/Deployment_web/metadata/name",
+			expected: false,
+		},
+		{
+			name:     "manifest key with dots",
+			patterns: []string{"data"},
+			path:     "ConfigMap_web.config.data.key",
+			pointer:  "/ConfigMap_web.config/data/key",
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rules, err := NewIgnoreRules(tt.patterns)
+			if err != nil {
+				t.Fatalf("failed to compile rules: %v", err)
+			}
+			if got := rules.Match(tt.path, tt.pointer); got != tt.expected {
+				t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewIgnoreRulesInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, pattern := range []string{"$.a[", "$.a[x]", "$.a..", "$."} {
+		if _, err := NewIgnoreRules([]string{pattern}); err == nil {
+			t.Errorf("expected %q to be rejected", pattern)
+		}
+	}
+}
+
+func TestIgnoreRulesFilter(t *testing.T) {
+	t.Parallel()
+
+	left := map[string]interface{}{
+		"Deployment_web": map[string]interface{}{
+			"metadata": map[string]interface{}{"annotations": map[string]interface{}{"checksum/config": "abc"}},
+			"spec":     map[string]interface{}{"replicas": 1},
+		},
+	}
+	right := map[string]interface{}{
+		"Deployment_web": map[string]interface{}{
+			"metadata": map[string]interface{}{"annotations": map[string]interface{}{"checksum/config": "def"}},
+			"spec":     map[string]interface{}{"replicas": 2},
+		},
+	}
+
+	rules, err := NewIgnoreRules([]string{"metadata.annotations.checksum/*"})
+	if err != nil {
+		t.Fatalf("failed to compile rules: %v", err)
+	}
+	kept, suppressed := rules.Filter(FindDifferencesWithValues(left, right))
+	if suppressed != 1 {
+		t.Errorf("expected 1 suppressed difference, got %d", suppressed)
+	}
+
+	expected := GroupedDifferencesDetailed{
+		"Deployment_web": {{Path: "Deployment_web.spec.replicas", DisplayText: "Deployment_web.spec.replicas"}},
+	}
+	if got := GroupDifferencesDetailed(left, right, kept); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestIgnoreRulesStrip(t *testing.T) {
+	t.Parallel()
+
+	manifest := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{"checksum/config": "abc", "team": "web"},
+		},
+		"spec": map[string]interface{}{
+			"replicas": 1,
+			"webhooks": []interface{}{map[string]interface{}{"name": "a", "caBundle": "Y2E="}},
+			"args":     []interface{}{"--seed=42", "--port=80"},
+		},
+	}
+
+	rules, err := NewIgnoreRules([]string{"metadata.annotations.checksum/*", "$..caBundle", "spec.args[0]"})
+	if err != nil {
+		t.Fatalf("failed to compile rules: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{"team": "web"},
+		},
+		"spec": map[string]interface{}{
+			"replicas": 1,
+			"webhooks": []interface{}{map[string]interface{}{"name": "a"}},
+			"args":     []interface{}{IgnoredValue, "--port=80"},
+		},
+	}
+	if got := rules.Strip("Deployment_web", manifest); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+	if _, ok := manifest["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})["checksum/config"]; !ok {
+		t.Errorf("expected the manifest to be left unchanged, got %+v", manifest)
+	}
+}
+
+func TestReadIgnoreFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "ignore.yaml")
+	content := `ignore:
+  - "**.annotations.checksum/*"
+  - $..caBundle
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write ignore file: %v", err)
+	}
+
+	patterns, err := ReadIgnoreFile(path)
+	if err != nil {
+		t.Fatalf("failed to read ignore file: %v", err)
+	}
+	if strings.Join(patterns, ",") != "**.annotations.checksum/*,$..caBundle" {
+		t.Errorf("unexpected patterns %v", patterns)
+	}
+}
@@ -0,0 +1,131 @@
+package yamldiff
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PatchOperation is an operation of an RFC 6902 JSON Patch
+type PatchOperation struct {
+	// Op is "add", "remove" or "replace"
+	Op string `json:"op"`
+	// Path is the JSON Pointer of the target location
+	Path string `json:"path"`
+	// Value is the new value of add and replace operations
+	Value interface{} `json:"value"`
+}
+
+// MarshalJSON omits the value of remove operations while keeping explicit null values of add and replace operations
+func (o PatchOperation) MarshalJSON() ([]byte, error) {
+	if o.Op == "remove" {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{Op: o.Op, Path: o.Path})
+	}
+	type operation PatchOperation
+	return json.Marshal(operation(o))
+}
+
+// JSONPatch derives the RFC 6902 JSON Patch that turns left into right from FindDifferencesWithValues.
+// Applying the patch to left reproduces right.
+func JSONPatch(left, right map[string]interface{}) []PatchOperation {
+	diffs := sortedDiffs(FindDifferencesWithValues(left, right))
+
+	var operations, removals []PatchOperation
+	for _, diff := range diffs {
+		switch diff.Type {
+		case DiffTypeAdded:
+			operations = append(operations, PatchOperation{Op: "add", Path: diff.Pointer, Value: diff.Right})
+		case DiffTypeRemoved:
+			removals = append(removals, PatchOperation{Op: "remove", Path: diff.Pointer})
+		default:
+			operations = append(operations, PatchOperation{Op: "replace", Path: diff.Pointer, Value: diff.Right})
+		}
+	}
+
+	// Elements are only removed from the end of a list. Removing the last one first keeps the indices of the others valid,
+	// while added elements are appended in ascending order.
+	for i := len(removals) - 1; i >= 0; i-- {
+		operations = append(operations, removals[i])
+	}
+	return operations
+}
+
+// MergePatch derives the RFC 7386 merge patch that turns left into right from FindDifferencesWithValues.
+// A merge patch replaces lists as a whole and cannot set a value to null, since null removes the key;
+// applying the patch to left reproduces right unless right contains null values.
+func MergePatch(left, right map[string]interface{}) map[string]interface{} {
+	patch := make(map[string]interface{})
+	for _, diff := range sortedDiffs(FindDifferencesWithValues(left, right)) {
+		node := patch
+		var current interface{} = right
+		tokens := pointerTokens(diff.Pointer)
+		for i, token := range tokens {
+			if i == len(tokens)-1 {
+				// Removed keys become null, which removes them
+				node[token] = diff.Right
+				break
+			}
+
+			child := current.(map[string]interface{})[token]
+			childMap, ok := child.(map[string]interface{})
+			if !ok {
+				// The difference is inside a list, which can only be replaced as a whole
+				node[token] = child
+				break
+			}
+
+			next, ok := node[token].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				node[token] = next
+			}
+			node, current = next, childMap
+		}
+	}
+	return patch
+}
+
+// sortedDiffs returns the differences ordered by their JSON Pointer, comparing list indices numerically
+func sortedDiffs(diffs map[string]DiffValue) []DiffValue {
+	sorted := make([]DiffValue, 0, len(diffs))
+	for _, diff := range diffs {
+		sorted = append(sorted, diff)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return comparePointers(sorted[i].Pointer, sorted[j].Pointer) < 0
+	})
+	return sorted
+}
+
+// comparePointers compares JSON Pointers token by token, comparing numeric tokens as numbers
+func comparePointers(a, b string) int {
+	tokensA, tokensB := pointerTokens(a), pointerTokens(b)
+	for i := 0; i < len(tokensA) && i < len(tokensB); i++ {
+		if tokensA[i] == tokensB[i] {
+			continue
+		}
+		indexA, errA := strconv.Atoi(tokensA[i])
+		indexB, errB := strconv.Atoi(tokensB[i])
+		if errA == nil && errB == nil {
+			return indexA - indexB
+		}
+		return strings.Compare(tokensA[i], tokensB[i])
+	}
+	return len(tokensA) - len(tokensB)
+}
+
+// pointerTokens splits a JSON Pointer into its unescaped reference tokens
+func pointerTokens(pointer string) []string {
+	if pointer == "" {
+		return nil
+	}
+	tokens := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens
+}
@@ -0,0 +1,290 @@
+package yamldiff
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+	"testing/quick"
+
+	jsonpatch "github.com/evanphx/json-patch"
+)
+
+func TestJSONPatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		left     map[string]interface{}
+		right    map[string]interface{}
+		expected string
+	}{
+		{
+			name:     "identical documents",
+			left:     map[string]interface{}{"a": 1},
+			right:    map[string]interface{}{"a": 1},
+			expected: `null`,
+		},
+		{
+			name: "replace, add and remove",
+			left: map[string]interface{}{
+				"spec":     map[string]interface{}{"replicas": 1},
+				"metadata": map[string]interface{}{"labels": map[string]interface{}{"tier": "web"}},
+			},
+			right: map[string]interface{}{
+				"spec":     map[string]interface{}{"replicas": 2, "paused": true},
+				"metadata": map[string]interface{}{"labels": map[string]interface{}{}},
+			},
+			expected: `[{"op":"add","path":"/spec/paused","value":true},{"op":"replace","path":"/spec/replicas","value":2},{"op":"remove","path":"/metadata/labels/tier"}]`,
+		},
+		{
+			name:     "list elements are removed from the end",
+			left:     map[string]interface{}{"args": []interface{}{"a", "b", "c", "d"}},
+			right:    map[string]interface{}{"args": []interface{}{"a", "x"}},
+			expected: `[{"op":"replace","path":"/args/1","value":"x"},{"op":"remove","path":"/args/3"},{"op":"remove","path":"/args/2"}]`,
+		},
+		{
+			name:     "escaped keys and null values",
+			left:     map[string]interface{}{"annotations": map[string]interface{}{"helm.sh/hook": "pre-install"}},
+			right:    map[string]interface{}{"annotations": map[string]interface{}{"helm.sh/hook": nil}},
+			expected: `[{"op":"replace","path":"/annotations/helm.sh~1hook","value":null}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := json.Marshal(JSONPatch(tt.left, tt.right))
+			if err != nil {
+				t.Fatalf("failed to marshal patch: %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		left     map[string]interface{}
+		right    map[string]interface{}
+		expected string
+	}{
+		{
+			name:     "identical documents",
+			left:     map[string]interface{}{"a": 1},
+			right:    map[string]interface{}{"a": 1},
+			expected: `{}`,
+		},
+		{
+			name: "nested changes and removed keys",
+			left: map[string]interface{}{
+				"spec":     map[string]interface{}{"replicas": 1, "paused": false},
+				"metadata": map[string]interface{}{"labels": map[string]interface{}{"tier": "web"}},
+			},
+			right: map[string]interface{}{
+				"spec":     map[string]interface{}{"replicas": 2, "paused": false},
+				"metadata": map[string]interface{}{"labels": map[string]interface{}{}},
+			},
+			expected: `{"metadata":{"labels":{"tier":null}},"spec":{"replicas":2}}`,
+		},
+		{
+			name: "lists are replaced as a whole",
+			left: map[string]interface{}{
+				"containers": []interface{}{map[string]interface{}{"name": "web", "image": "nginx:1.25"}},
+			},
+			right: map[string]interface{}{
+				"containers": []interface{}{map[string]interface{}{"name": "web", "image": "nginx:1.27"}},
+			},
+			expected: `{"containers":[{"image":"nginx:1.27","name":"web"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := json.Marshal(MergePatch(tt.left, tt.right))
+			if err != nil {
+				t.Fatalf("failed to marshal patch: %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+// randomDocument generates a random manifest-like document of maps, lists and scalars
+func randomDocument(r *rand.Rand, depth int, allowNull bool) map[string]interface{} {
+	doc := make(map[string]interface{})
+	for i := r.Intn(5); i >= 0; i-- {
+		doc[randomKey(r)] = randomValue(r, depth, allowNull)
+	}
+	return doc
+}
+
+// randomKey returns one of a few keys, including keys that need escaping in JSON Pointers
+func randomKey(r *rand.Rand) string {
+	keys := []string{"name", "spec", "image", "helm.sh/hook", "a~b", "0", "10", "2"}
+	return keys[r.Intn(len(keys))]
+}
+
+// randomValue generates a random scalar, list or map
+func randomValue(r *rand.Rand, depth int, allowNull bool) interface{} {
+	kinds := 5
+	if depth <= 0 {
+		kinds = 3
+	}
+	switch r.Intn(kinds) {
+	case 0:
+		if allowNull && r.Intn(3) == 0 {
+			return nil
+		}
+		return "v" + strconv.Itoa(r.Intn(3))
+	case 1:
+		return r.Intn(3)
+	case 2:
+		return r.Intn(2) == 0
+	case 3:
+		list := make([]interface{}, r.Intn(12))
+		for i := range list {
+			list[i] = randomValue(r, depth-1, allowNull)
+		}
+		return list
+	default:
+		return randomDocument(r, depth-1, allowNull)
+	}
+}
+
+// mutateDocument returns a copy of doc with random values modified, added and removed
+func mutateDocument(r *rand.Rand, doc map[string]interface{}, depth int, allowNull bool) map[string]interface{} {
+	mutated := make(map[string]interface{})
+	for key, value := range doc {
+		switch r.Intn(6) {
+		case 0:
+			// Drop the key
+		case 1:
+			mutated[key] = randomValue(r, depth, allowNull)
+		default:
+			mutated[key] = mutateValue(r, value, depth, allowNull)
+		}
+	}
+	if r.Intn(3) == 0 {
+		mutated[randomKey(r)] = randomValue(r, depth, allowNull)
+	}
+	return mutated
+}
+
+// mutateValue returns a copy of value with random parts modified
+func mutateValue(r *rand.Rand, value interface{}, depth int, allowNull bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return mutateDocument(r, v, depth-1, allowNull)
+	case []interface{}:
+		list := make([]interface{}, 0, len(v)+2)
+		for _, element := range v {
+			list = append(list, mutateValue(r, element, depth-1, allowNull))
+		}
+		// Shrink or grow the list
+		if len(list) > 0 && r.Intn(3) == 0 {
+			list = list[:r.Intn(len(list))]
+		}
+		for i := r.Intn(3); i > 0; i-- {
+			list = append(list, randomValue(r, depth-1, allowNull))
+		}
+		return list
+	default:
+		if r.Intn(2) == 0 {
+			return randomValue(r, 0, allowNull)
+		}
+		return value
+	}
+}
+
+// toJSON marshals v, failing the test on error
+func toJSON(t *testing.T, v interface{}) []byte {
+	t.Helper()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to marshal %v: %v", v, err)
+	}
+	return b
+}
+
+// equalJSON reports whether two JSON documents are semantically equal
+func equalJSON(t *testing.T, a, b []byte) bool {
+	t.Helper()
+
+	var va, vb interface{}
+	if err := json.Unmarshal(a, &va); err != nil {
+		t.Fatalf("failed to unmarshal %s: %v", a, err)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		t.Fatalf("failed to unmarshal %s: %v", b, err)
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func TestJSONPatchReproducesRight(t *testing.T) {
+	t.Parallel()
+
+	property := func(seed int64) bool {
+		r := rand.New(rand.NewSource(seed))
+		left := randomDocument(r, 3, true)
+		right := mutateDocument(r, left, 3, true)
+
+		patch, err := jsonpatch.DecodePatch(toJSON(t, JSONPatch(left, right)))
+		if err != nil {
+			t.Errorf("seed %d: invalid patch: %v", seed, err)
+			return false
+		}
+		applied, err := patch.Apply(toJSON(t, left))
+		if err != nil {
+			t.Errorf("seed %d: failed to apply patch: %v", seed, err)
+			return false
+		}
+		if !equalJSON(t, applied, toJSON(t, right)) {
+			t.Errorf("seed %d: patching %s produced %s, want %s", seed, toJSON(t, left), applied, toJSON(t, right))
+			return false
+		}
+		return true
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMergePatchReproducesRight(t *testing.T) {
+	t.Parallel()
+
+	// Merge patches cannot express null values, so the documents have none
+	property := func(seed int64) bool {
+		r := rand.New(rand.NewSource(seed))
+		left := randomDocument(r, 3, false)
+		right := mutateDocument(r, left, 3, false)
+
+		applied, err := jsonpatch.MergePatch(toJSON(t, left), toJSON(t, MergePatch(left, right)))
+		if err != nil {
+			t.Errorf("seed %d: failed to apply merge patch: %v", seed, err)
+			return false
+		}
+		if !equalJSON(t, applied, toJSON(t, right)) {
+			t.Errorf("seed %d: patching %s produced %s, want %s", seed, toJSON(t, left), applied, toJSON(t, right))
+			return false
+		}
+		return true
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,177 @@
+package yamldiff
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// payloadFormat is the format detected for a ConfigMap or Secret data entry
+type payloadFormat string
+
+const (
+	payloadFormatNone payloadFormat = ""
+	payloadFormatJSON payloadFormat = "json"
+	payloadFormatYAML payloadFormat = "yaml"
+	payloadFormatINI  payloadFormat = "ini"
+	payloadFormatText payloadFormat = "text"
+)
+
+// parsePayload detects the format of a data entry and parses it into the structure it is compared as:
+// a JSON or YAML mapping or list, an INI file of sections and "key = value" lines, or otherwise the lines of multi-line text.
+// Single-line values that are not JSON have no format.
+func parsePayload(text string) (interface{}, payloadFormat) {
+	if doc, ok := embeddedDocument(text); ok {
+		if trimmed := strings.TrimSpace(text); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			return doc, payloadFormatJSON
+		}
+		return doc, payloadFormatYAML
+	}
+	if !strings.Contains(strings.TrimSpace(text), "\n") {
+		return nil, payloadFormatNone
+	}
+	if ini, ok := parseINI(text); ok {
+		return ini, payloadFormatINI
+	}
+	return textLines(text), payloadFormatText
+}
+
+// parseINI parses an INI file into a map of its top-level keys and sections.
+// Files with lines that are neither comments, sections nor assignments, or with duplicate keys, are not INI files.
+func parseINI(text string) (map[string]interface{}, bool) {
+	root := make(map[string]interface{})
+	section := root
+	assignments := 0
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if _, exists := root[name]; exists {
+				return nil, false
+			}
+			section = make(map[string]interface{})
+			root[name] = section
+		default:
+			key, value, ok := strings.Cut(line, "=")
+			key = strings.TrimSpace(key)
+			if !ok || key == "" {
+				return nil, false
+			}
+			if _, exists := section[key]; exists {
+				return nil, false
+			}
+			section[key] = strings.TrimSpace(value)
+			assignments++
+		}
+	}
+	return root, assignments > 0
+}
+
+// textLines splits text into its lines, ignoring the final line break
+func textLines(text string) []string {
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// payloadLocation reports whether the JSON Pointer refers to an entry of ConfigMap or Secret data,
+// together with whether the entry is base64 encoded
+func payloadLocation(pointer string) (encoded bool, ok bool) {
+	tokens := pointerTokens(pointer)
+	if len(tokens) != 3 {
+		return false, false
+	}
+	kind, _, _ := strings.Cut(tokens[0], "_")
+	switch {
+	case kind == "ConfigMap" && tokens[1] == "data", kind == "Secret" && tokens[1] == "stringData":
+		return false, true
+	case kind == "ConfigMap" && tokens[1] == "binaryData", kind == "Secret" && tokens[1] == "data":
+		return true, true
+	}
+	return false, false
+}
+
+// findPayloadDifferences compares ConfigMap and Secret data entries as the documents they hold.
+// It reports false if the entries cannot be decoded or parsed, so that they are compared as plain values.
+func findPayloadDifferences(path, pointer string, left, right interface{}, opts CompareOptions, diffs map[string]DiffValue) bool {
+	encoded, ok := payloadLocation(pointer)
+	if !ok {
+		return false
+	}
+	leftText, ok := decodePayload(left, encoded)
+	if !ok {
+		return false
+	}
+	rightText, ok := decodePayload(right, encoded)
+	if !ok {
+		return false
+	}
+	if leftText == rightText {
+		return true
+	}
+
+	leftDoc, leftFormat := parsePayload(leftText)
+	rightDoc, rightFormat := parsePayload(rightText)
+	switch {
+	case leftFormat == payloadFormatNone && rightFormat == payloadFormatNone:
+		diffs[path] = DiffValue{Left: leftText, Right: rightText, Type: DiffTypeModified, Pointer: pointer}
+	case leftFormat == rightFormat && leftFormat != payloadFormatText:
+		findDifferencesWithValues(path, pointer, leftDoc, rightDoc, opts, diffs)
+	default:
+		// Entries whose format changed are compared line by line
+		findLineDifferences(path, pointer, textLines(leftText), textLines(rightText), diffs)
+	}
+	return true
+}
+
+// decodePayload returns the text of a data entry, decoding base64 entries. Binary data is not decoded.
+func decodePayload(v interface{}, encoded bool) (string, bool) {
+	text, ok := v.(string)
+	if !ok {
+		return "", false
+	}
+	if !encoded {
+		return text, true
+	}
+	decoded, err := base64.StdEncoding.DecodeString(text)
+	if err != nil || !utf8.Valid(decoded) {
+		return "", false
+	}
+	return string(decoded), true
+}
+
+// findLineDifferences records the differing lines of two texts. Changed lines are reported as "path:N" with their
+// line number in left, removed lines as "path:-N" and added lines as "path:+N" with their line number in right.
+func findLineDifferences(path, pointer string, left, right []string, diffs map[string]DiffValue) {
+	lines := diffLines(left, right)
+	for i := 0; i < len(lines); {
+		if lines[i].op == lineEqual {
+			i++
+			continue
+		}
+
+		// Pair the removed lines of a change with the lines added in their place
+		var removed, added []diffLine
+		for ; i < len(lines) && lines[i].op == lineDelete; i++ {
+			removed = append(removed, lines[i])
+		}
+		for ; i < len(lines) && lines[i].op == lineInsert; i++ {
+			added = append(added, lines[i])
+		}
+		for j := 0; j < len(removed) || j < len(added); j++ {
+			switch {
+			case j < len(removed) && j < len(added):
+				line := strconv.Itoa(removed[j].leftLine)
+				diffs[path+":"+line] = DiffValue{Left: removed[j].text, Right: added[j].text, Type: DiffTypeModified, Pointer: pointer + "/" + line}
+			case j < len(removed):
+				line := "-" + strconv.Itoa(removed[j].leftLine)
+				diffs[path+":"+line] = DiffValue{Left: removed[j].text, Type: DiffTypeRemoved, Pointer: pointer + "/" + line}
+			default:
+				line := "+" + strconv.Itoa(added[j].rightLine)
+				diffs[path+":"+line] = DiffValue{Right: added[j].text, Type: DiffTypeAdded, Pointer: pointer + "/" + line}
+			}
+		}
+	}
+}
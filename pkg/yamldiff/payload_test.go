@@ -0,0 +1,150 @@
+package yamldiff
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+)
+
+func TestFindDifferencesWithOptionsPayloads(t *testing.T) {
+	t.Parallel()
+
+	b64 := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+	configMap := func(data map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"ConfigMap_web": map[string]interface{}{"kind": "ConfigMap", "data": data}}
+	}
+	secret := func(data map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"Secret_web": map[string]interface{}{"kind": "Secret", "data": data}}
+	}
+
+	tests := []struct {
+		name     string
+		opts     CompareOptions
+		left     map[string]interface{}
+		right    map[string]interface{}
+		expected map[string]DiffValue
+	}{
+		{
+			name:  "YAML file",
+			opts:  CompareOptions{Payloads: true},
+			left:  configMap(map[string]interface{}{"alertmanager.yml": "route:\n  receiver: default\n  group_wait: 30s\n"}),
+			right: configMap(map[string]interface{}{"alertmanager.yml": "route:\n  receiver: pager\n  group_wait: 30s\n"}),
+			expected: map[string]DiffValue{
+				"ConfigMap_web.data.alertmanager.yml.route.receiver": {
+					Left: "default", Right: "pager", Type: DiffTypeModified, Pointer: "/ConfigMap_web/data/alertmanager.yml/route/receiver",
+				},
+			},
+		},
+		{
+			name:  "INI file",
+			opts:  CompareOptions{Payloads: true},
+			left:  configMap(map[string]interface{}{"grafana.ini": "instance_name = web\n\n[server]\n; public URL\nroot_url = http://localhost\n[auth]\ndisable_login_form = false\n"}),
+			right: configMap(map[string]interface{}{"grafana.ini": "instance_name = web\n\n[server]\n; public URL\nroot_url = https://grafana.example.com\n[auth]\ndisable_login_form = false\n"}),
+			expected: map[string]DiffValue{
+				"ConfigMap_web.data.grafana.ini.server.root_url": {
+					Left: "http://localhost", Right: "https://grafana.example.com", Type: DiffTypeModified, Pointer: "/ConfigMap_web/data/grafana.ini/server/root_url",
+				},
+			},
+		},
+		{
+			name:  "text lines",
+			opts:  CompareOptions{Payloads: true},
+			left:  configMap(map[string]interface{}{"entrypoint.sh": "#!/bin/sh\nset -e\necho start\nexec web\n"}),
+			right: configMap(map[string]interface{}{"entrypoint.sh": "#!/bin/sh\necho starting\nexec web --verbose\necho done\n"}),
+			expected: map[string]DiffValue{
+				"ConfigMap_web.data.entrypoint.sh:2": {Left: "set -e", Right: "echo starting", Type: DiffTypeModified, Pointer: "/ConfigMap_web/data/entrypoint.sh/2"},
+				"ConfigMap_web.data.entrypoint.sh:3": {Left: "echo start", Right: "exec web --verbose", Type: DiffTypeModified, Pointer: "/ConfigMap_web/data/entrypoint.sh/3"},
+				"ConfigMap_web.data.entrypoint.sh:4": {Left: "exec web", Right: "echo done", Type: DiffTypeModified, Pointer: "/ConfigMap_web/data/entrypoint.sh/4"},
+			},
+		},
+		{
+			name:  "added and removed lines",
+			opts:  CompareOptions{Payloads: true},
+			left:  configMap(map[string]interface{}{"hosts": "a\nb\nc\n"}),
+			right: configMap(map[string]interface{}{"hosts": "x\ny\na\nc\n"}),
+			expected: map[string]DiffValue{
+				"ConfigMap_web.data.hosts:+1": {Right: "x", Type: DiffTypeAdded, Pointer: "/ConfigMap_web/data/hosts/+1"},
+				"ConfigMap_web.data.hosts:+2": {Right: "y", Type: DiffTypeAdded, Pointer: "/ConfigMap_web/data/hosts/+2"},
+				"ConfigMap_web.data.hosts:-2": {Left: "b", Type: DiffTypeRemoved, Pointer: "/ConfigMap_web/data/hosts/-2"},
+			},
+		},
+		{
+			name:  "decoded Secret data",
+			opts:  CompareOptions{Payloads: true},
+			left:  secret(map[string]interface{}{"config.json": b64(`{"user": "admin", "password": "old"}`), "token": b64("abc")}),
+			right: secret(map[string]interface{}{"config.json": b64(`{"user": "admin", "password": "new"}`), "token": b64("abc"), "extra": b64("x")}),
+			expected: map[string]DiffValue{
+				"Secret_web.data.config.json.password": {Left: "old", Right: "new", Type: DiffTypeModified, Pointer: "/Secret_web/data/config.json/password"},
+				"Secret_web.data.extra":                {Right: b64("x"), Type: DiffTypeAdded, Pointer: "/Secret_web/data/extra"},
+			},
+		},
+		{
+			name:  "single-line Secret data",
+			opts:  CompareOptions{Payloads: true},
+			left:  secret(map[string]interface{}{"password": b64("old")}),
+			right: secret(map[string]interface{}{"password": b64("new")}),
+			expected: map[string]DiffValue{
+				"Secret_web.data.password": {Left: "old", Right: "new", Type: DiffTypeModified, Pointer: "/Secret_web/data/password"},
+			},
+		},
+		{
+			name:  "binary data is compared as it is",
+			opts:  CompareOptions{Payloads: true},
+			left:  map[string]interface{}{"ConfigMap_web": map[string]interface{}{"binaryData": map[string]interface{}{"logo.png": b64("\xff\x00")}}},
+			right: map[string]interface{}{"ConfigMap_web": map[string]interface{}{"binaryData": map[string]interface{}{"logo.png": b64("\xff\x01")}}},
+			expected: map[string]DiffValue{
+				"ConfigMap_web.binaryData.logo.png": {Left: b64("\xff\x00"), Right: b64("\xff\x01"), Type: DiffTypeModified, Pointer: "/ConfigMap_web/binaryData/logo.png"},
+			},
+		},
+		{
+			name:  "payloads are opaque by default",
+			left:  configMap(map[string]interface{}{"alertmanager.yml": "route:\n  receiver: default\n"}),
+			right: configMap(map[string]interface{}{"alertmanager.yml": "route:\n  receiver: pager\n"}),
+			expected: map[string]DiffValue{
+				"ConfigMap_web.data.alertmanager.yml": {
+					Left: "route:\n  receiver: default\n", Right: "route:\n  receiver: pager\n", Type: DiffTypeModified, Pointer: "/ConfigMap_web/data/alertmanager.yml",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := FindDifferencesWithOptions(tt.left, tt.right, tt.opts)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParsePayload(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		text     string
+		expected payloadFormat
+	}{
+		{name: "JSON", text: `{"a": 1}`, expected: payloadFormatJSON},
+		{name: "YAML", text: "a: 1\nb: 2\n", expected: payloadFormatYAML},
+		{name: "INI", text: "[server]\nhttp_port = 3000\n", expected: payloadFormatINI},
+		{name: "INI with duplicate keys is text", text: "[server]\nport = 1\nport = 2\n", expected: payloadFormatText},
+		{name: "text", text: "line one\nline two\n", expected: payloadFormatText},
+		{name: "single line", text: "s3cr3t", expected: payloadFormatNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, got := parsePayload(tt.text); got != tt.expected {
+				t.Errorf("parsePayload(%q) = %q, want %q", tt.text, got, tt.expected)
+			}
+		})
+	}
+}
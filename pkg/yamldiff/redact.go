@@ -0,0 +1,183 @@
+package yamldiff
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// minSensitiveValueLength is the length below which sensitive values are not searched for in other strings,
+// since short values such as "a" or "yes" would redact unrelated text
+const minSensitiveValueLength = 4
+
+// sensitiveKeyPattern matches keys holding credentials, e.g. "password", "apiKey" or "client_secret"
+var sensitiveKeyPattern = regexp.MustCompile(`(?i)(passw(or)?d|passphrase|secret|token|api[-_]?key|private[-_]?key|access[-_]?key|credentials?)`)
+
+// referenceKeyPattern matches keys that name or locate a credential instead of holding it, e.g. "secretName" or "tokenPath"
+var referenceKeyPattern = regexp.MustCompile(`(?i)(name|names|ref|path|file|enabled|existingsecret|ttl|expiration|seconds)$`)
+
+// IsSensitiveKey reports whether a key is named like one holding a credential, e.g. "password" or "apiToken"
+// but not "secretName" or "existingSecret"
+func IsSensitiveKey(key string) bool {
+	return sensitiveKeyPattern.MatchString(key) && !referenceKeyPattern.MatchString(key)
+}
+
+// Redactor replaces sensitive values by fingerprints before they are output. A value is sensitive if it is
+// Secret data, if its key is named like a credential (see IsSensitiveKey), if it is the value of an environment
+// variable named like one, or if it contains one of the sensitive values the Redactor was created with.
+// Equal values get equal fingerprints, so changes stay visible. A nil Redactor redacts nothing.
+type Redactor struct {
+	sensitiveValues []string
+}
+
+// NewRedactor creates a Redactor that also redacts strings containing any of the given values, e.g. the passwords
+// set in the chart values. Values shorter than four characters are ignored.
+func NewRedactor(sensitiveValues []string) *Redactor {
+	r := &Redactor{}
+	for _, value := range sensitiveValues {
+		if len(value) >= minSensitiveValueLength {
+			r.sensitiveValues = append(r.sensitiveValues, value)
+		}
+	}
+	return r
+}
+
+// Redact returns a copy of v, e.g. a manifest or a values tree, with its sensitive values redacted
+func (r *Redactor) Redact(v interface{}) interface{} {
+	if r == nil {
+		return v
+	}
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		secret := t["kind"] == "Secret"
+		redacted := make(map[string]interface{}, len(t))
+		for key, value := range t {
+			switch {
+			case secret && (key == "data" || key == "stringData"):
+				redacted[key] = RedactLeaves(value)
+			case sensitiveField(t, key, value):
+				redacted[key] = RedactedValue(value)
+			default:
+				redacted[key] = r.Redact(value)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(t))
+		for i, value := range t {
+			redacted[i] = r.Redact(value)
+		}
+		return redacted
+	case string:
+		if r.containsSensitiveValue(t) {
+			return RedactedValue(t)
+		}
+	}
+	return v
+}
+
+// RedactAt returns a copy of v with its sensitive values redacted, where v is found at the JSON Pointer
+// of a difference (see DiffValue) inside manifest, the side of the difference v belongs to
+func (r *Redactor) RedactAt(manifest interface{}, pointer string, v interface{}) interface{} {
+	if r == nil {
+		return v
+	}
+	tokens := pointerTokens(pointer)
+	if len(tokens) <= 1 {
+		return r.Redact(v)
+	}
+	tokens = tokens[1:]
+
+	doc, _ := manifest.(map[string]interface{})
+	if doc["kind"] == "Secret" && (tokens[0] == "data" || tokens[0] == "stringData") {
+		return RedactLeaves(v)
+	}
+
+	// The parent is missing for differences inside the payloads of ConfigMaps
+	parent, _ := lookupTokens(doc, tokens[:len(tokens)-1]).(map[string]interface{})
+	if sensitiveField(parent, tokens[len(tokens)-1], v) {
+		return RedactedValue(v)
+	}
+	return r.Redact(v)
+}
+
+// containsSensitiveValue reports whether s contains one of the sensitive values
+func (r *Redactor) containsSensitiveValue(s string) bool {
+	for _, value := range r.sensitiveValues {
+		if strings.Contains(s, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// sensitiveField reports whether the scalar value at key of parent is sensitive: its key is named like a credential,
+// or it is the value of an environment variable such as {"name": "DB_PASSWORD", "value": "..."}
+func sensitiveField(parent map[string]interface{}, key string, value interface{}) bool {
+	switch value.(type) {
+	case string, int, int64, uint64, float64:
+	default:
+		return false
+	}
+	if IsSensitiveKey(key) {
+		return true
+	}
+	name, _ := parent["name"].(string)
+	return key == "value" && IsSensitiveKey(name)
+}
+
+// RedactLeaves replaces every scalar within v by its fingerprint, e.g. for a value known to be sensitive
+func RedactLeaves(v interface{}) interface{} {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(t))
+		for key, value := range t {
+			redacted[key] = RedactLeaves(value)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(t))
+		for i, value := range t {
+			redacted[i] = RedactLeaves(value)
+		}
+		return redacted
+	}
+	return RedactedValue(v)
+}
+
+// lookupTokens returns the value at the reference tokens of a JSON Pointer inside v, or nil if there is none
+func lookupTokens(v interface{}, tokens []string) interface{} {
+	for _, token := range tokens {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			v = t[token]
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(t) {
+				return nil
+			}
+			v = t[index]
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+// RedactedValue replaces a sensitive value by a fingerprint of it, so that changed values still differ
+// while the values themselves are not shown
+func RedactedValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data = []byte(fmt.Sprint(v))
+	}
+	sum := sha256.Sum256(data)
+	return "(redacted sha256:" + hex.EncodeToString(sum[:])[:12] + ")"
+}
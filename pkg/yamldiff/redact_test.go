@@ -0,0 +1,171 @@
+package yamldiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsSensitiveKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		key      string
+		expected bool
+	}{
+		{key: "password", expected: true},
+		{key: "adminPassword", expected: true},
+		{key: "DB_PASSWD", expected: true},
+		{key: "apiKey", expected: true},
+		{key: "client_secret", expected: true},
+		{key: "token", expected: true},
+		{key: "credentials", expected: true},
+		{key: "secretName", expected: false},
+		{key: "existingSecret", expected: false},
+		{key: "secretKeyRef", expected: false},
+		{key: "tokenPath", expected: false},
+		{key: "replicas", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			t.Parallel()
+
+			if got := IsSensitiveKey(tt.key); got != tt.expected {
+				t.Errorf("IsSensitiveKey(%q) = %v, want %v", tt.key, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRedactorRedact(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		redactor *Redactor
+		input    interface{}
+		expected interface{}
+	}{
+		{
+			name:     "Secret data",
+			redactor: NewRedactor(nil),
+			input: map[string]interface{}{
+				"kind":     "Secret",
+				"metadata": map[string]interface{}{"name": "web"},
+				"data":     map[string]interface{}{"tls.key": "a2V5"},
+			},
+			expected: map[string]interface{}{
+				"kind":     "Secret",
+				"metadata": map[string]interface{}{"name": "web"},
+				"data":     map[string]interface{}{"tls.key": RedactedValue("a2V5")},
+			},
+		},
+		{
+			name:     "sensitive keys and environment variables",
+			redactor: NewRedactor(nil),
+			input: map[string]interface{}{
+				"auth": map[string]interface{}{"username": "admin", "password": "s3cret", "secretName": "web", "enabled": true},
+				"env": []interface{}{
+					map[string]interface{}{"name": "DB_PASSWORD", "value": "s3cret"},
+					map[string]interface{}{"name": "DB_HOST", "value": "db"},
+				},
+			},
+			expected: map[string]interface{}{
+				"auth": map[string]interface{}{"username": "admin", "password": RedactedValue("s3cret"), "secretName": "web", "enabled": true},
+				"env": []interface{}{
+					map[string]interface{}{"name": "DB_PASSWORD", "value": RedactedValue("s3cret")},
+					map[string]interface{}{"name": "DB_HOST", "value": "db"},
+				},
+			},
+		},
+		{
+			name:     "strings containing sensitive values",
+			redactor: NewRedactor([]string{"hunter2", "abc"}),
+			input:    map[string]interface{}{"args": []interface{}{"--dsn=postgres://app:hunter2@db", "--name=abc"}},
+			expected: map[string]interface{}{"args": []interface{}{RedactedValue("--dsn=postgres://app:hunter2@db"), "--name=abc"}},
+		},
+		{
+			name:     "nil redactor",
+			redactor: nil,
+			input:    map[string]interface{}{"password": "s3cret"},
+			expected: map[string]interface{}{"password": "s3cret"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.redactor.Redact(tt.input); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRedactorRedactAt(t *testing.T) {
+	t.Parallel()
+
+	secret := map[string]interface{}{"kind": "Secret", "data": map[string]interface{}{"config.yaml": "cGFzc3dvcmQ6IGEK"}}
+	deployment := map[string]interface{}{
+		"kind": "Deployment",
+		"spec": map[string]interface{}{
+			"env": []interface{}{map[string]interface{}{"name": "API_TOKEN", "value": "t0ken"}},
+		},
+	}
+	redactor := NewRedactor(nil)
+
+	tests := []struct {
+		name     string
+		manifest interface{}
+		pointer  string
+		value    interface{}
+		expected interface{}
+	}{
+		{
+			name:     "inside a decoded Secret payload",
+			manifest: secret,
+			pointer:  "/Secret_web/data/config.yaml/user",
+			value:    "admin",
+			expected: RedactedValue("admin"),
+		},
+		{
+			name:     "environment variable value",
+			manifest: deployment,
+			pointer:  "/Deployment_web/spec/env/0/value",
+			value:    "t0ken",
+			expected: RedactedValue("t0ken"),
+		},
+		{
+			name:     "sensitive key inside a ConfigMap payload",
+			manifest: map[string]interface{}{"kind": "ConfigMap"},
+			pointer:  "/ConfigMap_web/data/grafana.ini/security/admin_password",
+			value:    "admin",
+			expected: RedactedValue("admin"),
+		},
+		{
+			name:     "added environment variable",
+			manifest: deployment,
+			pointer:  "/Deployment_web/spec/env/0",
+			value:    map[string]interface{}{"name": "API_TOKEN", "value": "t0ken"},
+			expected: map[string]interface{}{"name": "API_TOKEN", "value": RedactedValue("t0ken")},
+		},
+		{
+			name:     "ordinary value",
+			manifest: deployment,
+			pointer:  "/Deployment_web/spec/replicas",
+			value:    2,
+			expected: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := redactor.RedactAt(tt.manifest, tt.pointer, tt.value); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package yamldiff
+
+import (
+	"errors"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// quantitySections are the keys whose entries are resource quantities, e.g. resources.limits.cpu or status.capacity.storage
+var quantitySections = map[string]bool{
+	"limits":      true,
+	"requests":    true,
+	"hard":        true,
+	"capacity":    true,
+	"allocatable": true,
+	"overhead":    true,
+}
+
+// limitRangeSections are the keys of LimitRange items whose entries are resource quantities
+var limitRangeSections = map[string]bool{
+	"max":                  true,
+	"min":                  true,
+	"default":              true,
+	"defaultRequest":       true,
+	"maxLimitRequestRatio": true,
+}
+
+// durationFields are the keys of duration fields in Kubernetes resources and common custom resources,
+// e.g. a cert-manager Certificate's renewBefore or a Prometheus Operator endpoint's scrapeTimeout
+var durationFields = map[string]bool{
+	"duration":           true,
+	"renewBefore":        true,
+	"interval":           true,
+	"retryInterval":      true,
+	"timeout":            true,
+	"scrapeInterval":     true,
+	"scrapeTimeout":      true,
+	"evaluationInterval": true,
+	"for":                true,
+	"keepFiringFor":      true,
+	"ttl":                true,
+}
+
+// metadataSections are the keys whose entries are arbitrary strings even if their keys look like duration fields
+var metadataSections = map[string]bool{
+	"annotations": true,
+	"labels":      true,
+	"data":        true,
+	"stringData":  true,
+}
+
+// semanticallyEqual reports whether two differing scalars mean the same: numbers of equal value,
+// equal resource quantities at the quantity fields of Kubernetes resources, or equal durations at duration fields
+func semanticallyEqual(pointer string, left, right interface{}) bool {
+	leftNumber, leftIsNumber := numberValue(left)
+	rightNumber, rightIsNumber := numberValue(right)
+	if leftIsNumber && rightIsNumber {
+		return leftNumber.Cmp(rightNumber) == 0
+	}
+
+	if isQuantityPointer(pointer) {
+		leftQuantity, leftErr := parseQuantity(left)
+		rightQuantity, rightErr := parseQuantity(right)
+		return leftErr == nil && rightErr == nil && leftQuantity.Cmp(rightQuantity) == 0
+	}
+
+	leftString, leftIsString := left.(string)
+	rightString, rightIsString := right.(string)
+	if leftIsString && rightIsString && isDurationPointer(pointer) {
+		leftDuration, leftErr := time.ParseDuration(leftString)
+		rightDuration, rightErr := time.ParseDuration(rightString)
+		return leftErr == nil && rightErr == nil && leftDuration == rightDuration
+	}
+	return false
+}
+
+// numberValue returns the exact value of a number decoded from YAML
+func numberValue(v interface{}) (*big.Rat, bool) {
+	switch n := v.(type) {
+	case int:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case int64:
+		return new(big.Rat).SetInt64(n), true
+	case uint64:
+		return new(big.Rat).SetUint64(n), true
+	case float64:
+		// NaN and infinities have no exact value
+		r := new(big.Rat).SetFloat64(n)
+		return r, r != nil
+	}
+	return nil, false
+}
+
+// isQuantityPointer reports whether the JSON Pointer refers to a resource quantity:
+// an entry of limits, requests and similar sections, an entry of a LimitRange item, or an emptyDir sizeLimit
+func isQuantityPointer(pointer string) bool {
+	tokens := pointerTokens(pointer)
+	n := len(tokens)
+	switch {
+	case n >= 1 && tokens[n-1] == "sizeLimit":
+		return true
+	case n >= 2 && quantitySections[tokens[n-2]]:
+		return true
+	case n >= 4 && tokens[n-4] == "limits" && limitRangeSections[tokens[n-2]]:
+		_, err := strconv.Atoi(tokens[n-3])
+		return err == nil
+	}
+	return false
+}
+
+// isDurationPointer reports whether the JSON Pointer refers to a duration field, so that strings such as
+// container arguments or annotations are still compared literally
+func isDurationPointer(pointer string) bool {
+	tokens := pointerTokens(pointer)
+	n := len(tokens)
+	return n >= 2 && durationFields[tokens[n-1]] && !metadataSections[tokens[n-2]]
+}
+
+// parseQuantity parses a resource quantity written as a string or a number
+func parseQuantity(v interface{}) (resource.Quantity, error) {
+	switch q := v.(type) {
+	case string:
+		return resource.ParseQuantity(q)
+	case int:
+		return resource.ParseQuantity(strconv.Itoa(q))
+	case float64:
+		return resource.ParseQuantity(strconv.FormatFloat(q, 'f', -1, 64))
+	}
+	return resource.Quantity{}, errors.New("not a quantity")
+}
+
+// embeddedDocuments parses two strings holding JSON or YAML documents, e.g. a configuration file in a ConfigMap.
+// It succeeds only if both strings hold a single mapping or both hold a single sequence.
+func embeddedDocuments(left, right interface{}) (interface{}, interface{}, bool) {
+	leftDoc, ok := embeddedDocument(left)
+	if !ok {
+		return nil, nil, false
+	}
+	rightDoc, ok := embeddedDocument(right)
+	if !ok {
+		return nil, nil, false
+	}
+
+	_, leftIsMap := leftDoc.(map[string]interface{})
+	_, rightIsMap := rightDoc.(map[string]interface{})
+	if leftIsMap != rightIsMap {
+		return nil, nil, false
+	}
+	return leftDoc, rightDoc, true
+}
+
+// embeddedDocument parses a string holding a single JSON or YAML mapping or sequence.
+// Single-line strings only count if they look like JSON, so that values such as "key: value" stay strings.
+func embeddedDocument(v interface{}) (interface{}, bool) {
+	text, ok := v.(string)
+	if !ok {
+		return nil, false
+	}
+	trimmed := strings.TrimSpace(text)
+	if !strings.Contains(trimmed, "\n") && !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return nil, false
+	}
+
+	decoder := yaml.NewDecoder(strings.NewReader(text))
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, false
+	}
+	// Differences in further documents would be missed, so multi-document strings are compared as strings
+	var next interface{}
+	if err := decoder.Decode(&next); !errors.Is(err, io.EOF) {
+		return nil, false
+	}
+
+	switch doc.(type) {
+	case map[string]interface{}, []interface{}:
+		return doc, true
+	}
+	return nil, false
+}
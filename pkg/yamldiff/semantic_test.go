@@ -0,0 +1,170 @@
+package yamldiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindDifferencesWithOptionsSemantic(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		left     map[string]interface{}
+		right    map[string]interface{}
+		expected map[string]DiffValue
+	}{
+		{
+			name: "equal quantities",
+			left: map[string]interface{}{"Deployment_web": map[string]interface{}{"resources": map[string]interface{}{
+				"limits":   map[string]interface{}{"cpu": "1000m", "memory": "1Gi"},
+				"requests": map[string]interface{}{"cpu": 0.5, "memory": "512Mi"},
+			}}},
+			right: map[string]interface{}{"Deployment_web": map[string]interface{}{"resources": map[string]interface{}{
+				"limits":   map[string]interface{}{"cpu": 1, "memory": "1024Mi"},
+				"requests": map[string]interface{}{"cpu": "500m", "memory": "0.5Gi"},
+			}}},
+			expected: map[string]DiffValue{},
+		},
+		{
+			name:  "different quantities",
+			left:  map[string]interface{}{"Deployment_web": map[string]interface{}{"limits": map[string]interface{}{"memory": "1Gi"}}},
+			right: map[string]interface{}{"Deployment_web": map[string]interface{}{"limits": map[string]interface{}{"memory": "1G"}}},
+			expected: map[string]DiffValue{
+				"Deployment_web.limits.memory": {Left: "1Gi", Right: "1G", Type: DiffTypeModified, Pointer: "/Deployment_web/limits/memory"},
+			},
+		},
+		{
+			name: "LimitRange and emptyDir quantities",
+			left: map[string]interface{}{
+				"LimitRange_web": map[string]interface{}{"limits": []interface{}{map[string]interface{}{"default": map[string]interface{}{"cpu": "2"}}}},
+				"Pod_web":        map[string]interface{}{"emptyDir": map[string]interface{}{"sizeLimit": "1Gi"}},
+			},
+			right: map[string]interface{}{
+				"LimitRange_web": map[string]interface{}{"limits": []interface{}{map[string]interface{}{"default": map[string]interface{}{"cpu": "2000m"}}}},
+				"Pod_web":        map[string]interface{}{"emptyDir": map[string]interface{}{"sizeLimit": "1024Mi"}},
+			},
+			expected: map[string]DiffValue{},
+		},
+		{
+			name:  "quantities elsewhere are strings",
+			left:  map[string]interface{}{"Deployment_web": map[string]interface{}{"image": map[string]interface{}{"tag": "1"}}},
+			right: map[string]interface{}{"Deployment_web": map[string]interface{}{"image": map[string]interface{}{"tag": "1000m"}}},
+			expected: map[string]DiffValue{
+				"Deployment_web.image.tag": {Left: "1", Right: "1000m", Type: DiffTypeModified, Pointer: "/Deployment_web/image/tag"},
+			},
+		},
+		{
+			name:     "numbers of different types",
+			left:     map[string]interface{}{"Deployment_web": map[string]interface{}{"replicas": 2, "ratio": 0.5}},
+			right:    map[string]interface{}{"Deployment_web": map[string]interface{}{"replicas": 2.0, "ratio": 0.5}},
+			expected: map[string]DiffValue{},
+		},
+		{
+			name:  "number and string",
+			left:  map[string]interface{}{"Service_web": map[string]interface{}{"port": 80}},
+			right: map[string]interface{}{"Service_web": map[string]interface{}{"port": "80"}},
+			expected: map[string]DiffValue{
+				"Service_web.port": {Left: 80, Right: "80", Type: DiffTypeModified, Pointer: "/Service_web/port"},
+			},
+		},
+		{
+			name:     "equal durations",
+			left:     map[string]interface{}{"Certificate_web": map[string]interface{}{"duration": "2160h", "renewBefore": "60s"}},
+			right:    map[string]interface{}{"Certificate_web": map[string]interface{}{"duration": "2160h0m0s", "renewBefore": "1m"}},
+			expected: map[string]DiffValue{},
+		},
+		{
+			name: "durations elsewhere are strings",
+			left: map[string]interface{}{"Deployment_web": map[string]interface{}{
+				"args":     []interface{}{"60s"},
+				"env":      []interface{}{map[string]interface{}{"name": "TIMEOUT", "value": "60s"}},
+				"metadata": map[string]interface{}{"annotations": map[string]interface{}{"timeout": "60s"}},
+			}},
+			right: map[string]interface{}{"Deployment_web": map[string]interface{}{
+				"args":     []interface{}{"1m"},
+				"env":      []interface{}{map[string]interface{}{"name": "TIMEOUT", "value": "1m"}},
+				"metadata": map[string]interface{}{"annotations": map[string]interface{}{"timeout": "1m"}},
+			}},
+			expected: map[string]DiffValue{
+				"Deployment_web.args[0]":                      {Left: "60s", Right: "1m", Type: DiffTypeModified, Pointer: "/Deployment_web/args/0"},
+				"Deployment_web.env[0].value":                 {Left: "60s", Right: "1m", Type: DiffTypeModified, Pointer: "/Deployment_web/env/0/value"},
+				"Deployment_web.metadata.annotations.timeout": {Left: "60s", Right: "1m", Type: DiffTypeModified, Pointer: "/Deployment_web/metadata/annotations/timeout"},
+			},
+		},
+		{
+			name: "embedded YAML document",
+			left: map[string]interface{}{"ConfigMap_prometheus": map[string]interface{}{"data": map[string]interface{}{
+				"prometheus.yml": "global:\n  scrape_interval: 30s\n  evaluation_interval: 30s\n",
+			}}},
+			right: map[string]interface{}{"ConfigMap_prometheus": map[string]interface{}{"data": map[string]interface{}{
+				"prometheus.yml": "global:\n  evaluation_interval: 30s\n  scrape_interval: 1m\n",
+			}}},
+			expected: map[string]DiffValue{
+				"ConfigMap_prometheus.data.prometheus.yml.global.scrape_interval": {
+					Left:    "30s",
+					Right:   "1m",
+					Type:    DiffTypeModified,
+					Pointer: "/ConfigMap_prometheus/data/prometheus.yml/global/scrape_interval",
+				},
+			},
+		},
+		{
+			name: "embedded JSON document",
+			left: map[string]interface{}{"ConfigMap_dashboards": map[string]interface{}{"data": map[string]interface{}{
+				"dashboard.json": `{"title": "web", "panels": [{"id": 1}]}`,
+			}}},
+			right: map[string]interface{}{"ConfigMap_dashboards": map[string]interface{}{"data": map[string]interface{}{
+				"dashboard.json": "{\n  \"panels\": [{\"id\": 1.0}, {\"id\": 2}],\n  \"title\": \"web\"\n}",
+			}}},
+			expected: map[string]DiffValue{
+				"ConfigMap_dashboards.data.dashboard.json.panels[1]": {
+					Left:    nil,
+					Right:   map[string]interface{}{"id": 2},
+					Type:    DiffTypeAdded,
+					Pointer: "/ConfigMap_dashboards/data/dashboard.json/panels/1",
+				},
+			},
+		},
+		{
+			name:  "plain strings stay strings",
+			left:  map[string]interface{}{"ConfigMap_web": map[string]interface{}{"data": map[string]interface{}{"note": "a: b", "script": "echo a\necho b\n"}}},
+			right: map[string]interface{}{"ConfigMap_web": map[string]interface{}{"data": map[string]interface{}{"note": "a: c", "script": "echo a\necho c\n"}}},
+			expected: map[string]DiffValue{
+				"ConfigMap_web.data.note":   {Left: "a: b", Right: "a: c", Type: DiffTypeModified, Pointer: "/ConfigMap_web/data/note"},
+				"ConfigMap_web.data.script": {Left: "echo a\necho b\n", Right: "echo a\necho c\n", Type: DiffTypeModified, Pointer: "/ConfigMap_web/data/script"},
+			},
+		},
+		{
+			name:  "multi-document strings stay strings",
+			left:  map[string]interface{}{"ConfigMap_web": map[string]interface{}{"data": map[string]interface{}{"docs": "a: 1\n---\nb: 1\n"}}},
+			right: map[string]interface{}{"ConfigMap_web": map[string]interface{}{"data": map[string]interface{}{"docs": "a: 1\n---\nb: 2\n"}}},
+			expected: map[string]DiffValue{
+				"ConfigMap_web.data.docs": {Left: "a: 1\n---\nb: 1\n", Right: "a: 1\n---\nb: 2\n", Type: DiffTypeModified, Pointer: "/ConfigMap_web/data/docs"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := FindDifferencesWithOptions(tt.left, tt.right, CompareOptions{Semantic: true})
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFindDifferencesWithOptionsLiteral(t *testing.T) {
+	t.Parallel()
+
+	left := map[string]interface{}{"Deployment_web": map[string]interface{}{"limits": map[string]interface{}{"cpu": "1000m"}, "replicas": 2}}
+	right := map[string]interface{}{"Deployment_web": map[string]interface{}{"limits": map[string]interface{}{"cpu": "1"}, "replicas": 2.0}}
+
+	got := FindDifferencesWithOptions(left, right, CompareOptions{})
+	if len(got) != 2 {
+		t.Errorf("expected both differences without semantic comparison, got %+v", got)
+	}
+}
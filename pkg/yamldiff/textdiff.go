@@ -0,0 +1,349 @@
+package yamldiff
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// contextLines is the number of unchanged lines shown around each change, as in diff -u
+const contextLines = 3
+
+// maxEditDistance bounds the search for a shortest line diff; texts differing in more lines are diffed as
+// removing all lines of one and adding all lines of the other
+const maxEditDistance = 1000
+
+// lineOp is the operation of a line in a line diff
+type lineOp int
+
+const (
+	lineEqual lineOp = iota
+	lineDelete
+	lineInsert
+)
+
+// diffLine is a line of a line diff with its 1-based line numbers in the left and right text
+type diffLine struct {
+	op        lineOp
+	text      string
+	leftLine  int
+	rightLine int
+}
+
+// UnifiedDiff re-serializes both versions of the manifest under key and renders a diff -u style hunk per change.
+// A nil version is treated as an added or removed manifest. It returns an empty string if the versions are equal.
+func UnifiedDiff(key string, left, right interface{}) string {
+	hunks := manifestHunks(left, right)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	leftName, rightName := "a/"+key, "b/"+key
+	if left == nil {
+		leftName = "/dev/null"
+	}
+	if right == nil {
+		rightName = "/dev/null"
+	}
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", leftName, rightName)
+
+	for _, hunk := range hunks {
+		b.WriteString(hunkHeader(hunk))
+		for _, line := range hunk {
+			switch line.op {
+			case lineEqual:
+				b.WriteString(" " + line.text + "\n")
+			case lineDelete:
+				b.WriteString("-" + line.text + "\n")
+			case lineInsert:
+				b.WriteString("+" + line.text + "\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// SideBySideDiff re-serializes both versions of the manifest under key and renders each change in two columns,
+// marking changed lines with "|", removed lines with "<" and added lines with ">" as diff -y does.
+// width is the total line width; it returns an empty string if the versions are equal.
+func SideBySideDiff(key string, left, right interface{}, width int) string {
+	hunks := manifestHunks(left, right)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	// Two columns separated by " x "
+	column := max((width-3)/2, 10)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", key)
+	for _, hunk := range hunks {
+		b.WriteString(hunkHeader(hunk))
+		for i := 0; i < len(hunk); {
+			if hunk[i].op == lineEqual {
+				b.WriteString(sideBySideRow(hunk[i].text, " ", hunk[i].text, column))
+				i++
+				continue
+			}
+
+			// Pair the removed lines of a change with its added lines
+			var deleted, inserted []string
+			for ; i < len(hunk) && hunk[i].op == lineDelete; i++ {
+				deleted = append(deleted, hunk[i].text)
+			}
+			for ; i < len(hunk) && hunk[i].op == lineInsert; i++ {
+				inserted = append(inserted, hunk[i].text)
+			}
+			for j := 0; j < max(len(deleted), len(inserted)); j++ {
+				switch {
+				case j >= len(deleted):
+					b.WriteString(sideBySideRow("", ">", inserted[j], column))
+				case j >= len(inserted):
+					b.WriteString(sideBySideRow(deleted[j], "<", "", column))
+				default:
+					b.WriteString(sideBySideRow(deleted[j], "|", inserted[j], column))
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+// sideBySideRow formats one row of a side-by-side diff, truncating both texts to the column width
+func sideBySideRow(left, marker, right string, column int) string {
+	row := padRight(truncate(left, column), column) + " " + marker + " " + truncate(right, column)
+	return strings.TrimRight(row, " ") + "\n"
+}
+
+// truncate shortens s to at most width runes
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// padRight pads s with spaces to width runes
+func padRight(s string, width int) string {
+	if n := len([]rune(s)); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// hunkHeader returns the "@@ -l,s +l,s @@" line of a hunk
+func hunkHeader(hunk []diffLine) string {
+	leftStart, leftCount, rightStart, rightCount := 0, 0, 0, 0
+	for _, line := range hunk {
+		if line.op != lineInsert {
+			if leftCount == 0 {
+				leftStart = line.leftLine
+			}
+			leftCount++
+		}
+		if line.op != lineDelete {
+			if rightCount == 0 {
+				rightStart = line.rightLine
+			}
+			rightCount++
+		}
+	}
+	// diff -u numbers an empty range by the line before it
+	if leftCount == 0 {
+		leftStart = hunk[0].leftLine - 1
+	}
+	if rightCount == 0 {
+		rightStart = hunk[0].rightLine - 1
+	}
+	return fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(leftStart, leftCount), hunkRange(rightStart, rightCount))
+}
+
+// hunkRange formats the range of a hunk header, omitting the count of a single line as diff -u does
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// manifestHunks serializes both versions of a manifest and groups their line diff into hunks with context
+func manifestHunks(left, right interface{}) [][]diffLine {
+	lines := diffLines(manifestLines(left), manifestLines(right))
+
+	var hunks [][]diffLine
+	start, end := -1, -1
+	for i, line := range lines {
+		if line.op == lineEqual {
+			continue
+		}
+		from, to := max(i-contextLines, 0), min(i+contextLines+1, len(lines))
+		if start >= 0 && from > end {
+			hunks = append(hunks, lines[start:end])
+			start = -1
+		}
+		if start < 0 {
+			start = from
+		}
+		end = to
+	}
+	if start >= 0 {
+		hunks = append(hunks, lines[start:end])
+	}
+	return hunks
+}
+
+// manifestLines serializes a manifest as YAML and splits it into lines; a nil manifest has no lines
+func manifestLines(manifest interface{}) []string {
+	if manifest == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(manifest); err != nil {
+		// Manifests come from parsed YAML, so this only happens for values that cannot be encoded at all
+		return []string{fmt.Sprintf("%v", manifest)}
+	}
+	_ = encoder.Close()
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+// diffLines computes a shortest line diff of left and right, listing the removed lines of each change before its added lines.
+// The common prefix and suffix are skipped before running Myers' algorithm on the rest, so the usual few changed lines
+// of a large manifest cost little time and memory.
+func diffLines(left, right []string) []diffLine {
+	prefix := 0
+	for prefix < len(left) && prefix < len(right) && left[prefix] == right[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(left)-prefix && suffix < len(right)-prefix && left[len(left)-1-suffix] == right[len(right)-1-suffix] {
+		suffix++
+	}
+	edits := shortestEdit(left[prefix:len(left)-suffix], right[prefix:len(right)-suffix])
+
+	lines := make([]diffLine, 0, len(left)+len(right))
+	leftLine, rightLine := 1, 1
+	add := func(op lineOp, text string) {
+		lines = append(lines, diffLine{op: op, text: text, leftLine: leftLine, rightLine: rightLine})
+		if op != lineInsert {
+			leftLine++
+		}
+		if op != lineDelete {
+			rightLine++
+		}
+	}
+
+	for _, text := range left[:prefix] {
+		add(lineEqual, text)
+	}
+	for i := 0; i < len(edits); {
+		if edits[i].op == lineEqual {
+			add(lineEqual, edits[i].text)
+			i++
+			continue
+		}
+
+		// Move the removed lines of a change in front of its added lines
+		end := i
+		for end < len(edits) && edits[end].op != lineEqual {
+			end++
+		}
+		for _, op := range []lineOp{lineDelete, lineInsert} {
+			for _, edit := range edits[i:end] {
+				if edit.op == op {
+					add(op, edit.text)
+				}
+			}
+		}
+		i = end
+	}
+	for _, text := range left[len(left)-suffix:] {
+		add(lineEqual, text)
+	}
+	return lines
+}
+
+// shortestEdit returns a shortest edit script turning l into r, without line numbers.
+// It runs Myers' O((n+m)·d) algorithm and keeps the furthest reaching paths of each step to trace the script back,
+// which takes O(d²) memory for d differing lines. Beyond maxEditDistance it replaces all of l with all of r.
+func shortestEdit(l, r []string) []diffLine {
+	n, m := len(l), len(r)
+	offset := n + m
+	// v[offset+k] is the furthest x reached on diagonal k = x - y
+	v := make([]int, 2*offset+2)
+	// trace[d][k+d] is the furthest x reached on diagonal k after d edits
+	var trace [][]int
+
+search:
+	for d := 0; d <= n+m; d++ {
+		if d > maxEditDistance {
+			edits := make([]diffLine, 0, n+m)
+			for _, text := range l {
+				edits = append(edits, diffLine{op: lineDelete, text: text})
+			}
+			for _, text := range r {
+				edits = append(edits, diffLine{op: lineInsert, text: text})
+			}
+			return edits
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && l[x] == r[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+				break search
+			}
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+	}
+
+	var edits []diffLine
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		furthest := func(k int) int { return prev[k+d-1] }
+
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && furthest(k-1) < furthest(k+1)) {
+			prevK = k + 1
+		}
+		prevX := furthest(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			edits = append(edits, diffLine{op: lineEqual, text: l[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			edits = append(edits, diffLine{op: lineInsert, text: r[y-1]})
+		} else {
+			edits = append(edits, diffLine{op: lineDelete, text: l[x-1]})
+		}
+		x, y = prevX, prevY
+	}
+	for ; x > 0; x-- {
+		edits = append(edits, diffLine{op: lineEqual, text: l[x-1]})
+	}
+
+	slices.Reverse(edits)
+	return edits
+}
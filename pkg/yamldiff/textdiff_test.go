@@ -0,0 +1,256 @@
+package yamldiff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	t.Parallel()
+
+	deployment := func(replicas int, image string) map[string]interface{} {
+		return map[string]interface{}{
+			"kind":     "Deployment",
+			"metadata": map[string]interface{}{"name": "web"},
+			"spec": map[string]interface{}{
+				"replicas": replicas,
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{"name": "web", "image": image},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		left     interface{}
+		right    interface{}
+		expected string
+	}{
+		{
+			name:     "identical manifests",
+			left:     deployment(1, "nginx:1.25"),
+			right:    deployment(1, "nginx:1.25"),
+			expected: "",
+		},
+		{
+			name:  "modified field",
+			left:  deployment(1, "nginx:1.25"),
+			right: deployment(1, "nginx:1.27"),
+			expected: `--- a/Deployment_web
++++ b/Deployment_web
+@@ -6,5 +6,5 @@
+   template:
+     spec:
+       containers:
+-        - image: nginx:1.25
++        - image: nginx:1.27
+           name: web
+`,
+		},
+		{
+			name:  "changes far apart are separate hunks",
+			left:  map[string]interface{}{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5, "f": 6, "g": 7, "h": 8, "i": 9},
+			right: map[string]interface{}{"a": 0, "b": 2, "c": 3, "d": 4, "e": 5, "f": 6, "g": 7, "h": 8, "i": 0},
+			expected: `--- a/Deployment_web
++++ b/Deployment_web
+@@ -1,4 +1,4 @@
+-a: 1
++a: 0
+ b: 2
+ c: 3
+ d: 4
+@@ -6,4 +6,4 @@
+ f: 6
+ g: 7
+ h: 8
+-i: 9
++i: 0
+`,
+		},
+		{
+			name:  "added manifest",
+			left:  nil,
+			right: map[string]interface{}{"kind": "Deployment", "metadata": map[string]interface{}{"name": "web"}},
+			expected: `--- /dev/null
++++ b/Deployment_web
+@@ -0,0 +1,3 @@
++kind: Deployment
++metadata:
++  name: web
+`,
+		},
+		{
+			name:  "removed manifest",
+			left:  map[string]interface{}{"kind": "Deployment"},
+			right: nil,
+			expected: `--- a/Deployment_web
++++ /dev/null
+@@ -1 +0,0 @@
+-kind: Deployment
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := UnifiedDiff("Deployment_web", tt.left, tt.right); got != tt.expected {
+				t.Errorf("UnifiedDiff() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSideBySideDiff(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		left     interface{}
+		right    interface{}
+		width    int
+		expected string
+	}{
+		{
+			name:     "identical manifests",
+			left:     map[string]interface{}{"a": 1},
+			right:    map[string]interface{}{"a": 1},
+			width:    40,
+			expected: "",
+		},
+		{
+			name:  "modified, removed and added lines",
+			left:  map[string]interface{}{"a": 1, "b": 2, "c": 3},
+			right: map[string]interface{}{"a": 1, "b": 20, "d": 4, "e": 5},
+			width: 23,
+			expected: `ConfigMap_app
+@@ -1,3 +1,4 @@
+a: 1         a: 1
+b: 2       | b: 20
+c: 3       | d: 4
+           > e: 5
+`,
+		},
+		{
+			name:  "long lines are truncated",
+			left:  map[string]interface{}{"key": "a very long value"},
+			right: map[string]interface{}{"key": "another very long value"},
+			width: 33,
+			expected: `ConfigMap_app
+@@ -1 +1 @@
+key: a very lo… | key: another v…
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := SideBySideDiff("ConfigMap_app", tt.left, tt.right, tt.width); got != tt.expected {
+				t.Errorf("SideBySideDiff() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	t.Parallel()
+
+	numbered := func(prefix string, from, to int) []string {
+		var lines []string
+		for i := from; i < to; i++ {
+			lines = append(lines, fmt.Sprintf("%s%d", prefix, i))
+		}
+		return lines
+	}
+	concat := func(parts ...[]string) []string {
+		var lines []string
+		for _, part := range parts {
+			lines = append(lines, part...)
+		}
+		return lines
+	}
+
+	tests := []struct {
+		name  string
+		left  []string
+		right []string
+		// changes is the number of removed and added lines of a shortest diff
+		changes int
+	}{
+		{name: "empty", changes: 0},
+		{name: "added text", right: []string{"a", "b"}, changes: 2},
+		{name: "removed text", left: []string{"a", "b"}, changes: 2},
+		{name: "identical", left: []string{"a", "b", "c"}, right: []string{"a", "b", "c"}, changes: 0},
+		{name: "replaced line", left: []string{"a", "b", "c"}, right: []string{"a", "x", "c"}, changes: 2},
+		{name: "interleaved changes", left: strings.Split("abcabba", ""), right: strings.Split("cbabac", ""), changes: 5},
+		{name: "moved block", left: []string{"a", "b", "c", "d"}, right: []string{"c", "d", "a", "b"}, changes: 4},
+		{
+			name:    "few changes in a large text",
+			left:    concat(numbered("line", 0, 100000), []string{"old"}, numbered("line", 100000, 200000)),
+			right:   concat(numbered("line", 0, 50000), []string{"new"}, numbered("line", 50000, 200000)),
+			changes: 2,
+		},
+		{
+			name:    "large text without common lines",
+			left:    numbered("left", 0, 50000),
+			right:   numbered("right", 0, 50000),
+			changes: 100000,
+		},
+		{
+			name:    "more changes than searched for",
+			left:    concat(numbered("left", 0, maxEditDistance), []string{"common"}, numbered("left", maxEditDistance, 2*maxEditDistance)),
+			right:   concat(numbered("right", 0, maxEditDistance), []string{"common"}, numbered("right", maxEditDistance, 2*maxEditDistance)),
+			changes: 4*maxEditDistance + 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			lines := diffLines(tt.left, tt.right)
+
+			var left, right []string
+			changes := 0
+			for i, line := range lines {
+				if line.op != lineInsert {
+					if line.leftLine != len(left)+1 {
+						t.Fatalf("line %d: expected left line %d, got %d", i, len(left)+1, line.leftLine)
+					}
+					left = append(left, line.text)
+				}
+				if line.op != lineDelete {
+					if line.rightLine != len(right)+1 {
+						t.Fatalf("line %d: expected right line %d, got %d", i, len(right)+1, line.rightLine)
+					}
+					right = append(right, line.text)
+				}
+				if line.op != lineEqual {
+					changes++
+				}
+				if line.op == lineDelete && i > 0 && lines[i-1].op == lineInsert {
+					t.Fatalf("line %d: expected removed lines before added lines", i)
+				}
+			}
+
+			if strings.Join(left, "\n") != strings.Join(tt.left, "\n") {
+				t.Errorf("left side of the diff does not match the left text")
+			}
+			if strings.Join(right, "\n") != strings.Join(tt.right, "\n") {
+				t.Errorf("right side of the diff does not match the right text")
+			}
+			if changes != tt.changes {
+				t.Errorf("expected %d changed lines, got %d", tt.changes, changes)
+			}
+		})
+	}
+}